@@ -1,6 +1,6 @@
 module github.com/AlpacaLabs/api-account
 
-go 1.14
+go 1.16
 
 require (
 	github.com/AlpacaLabs/go-config v0.0.0-20200513234945-e6f2b4c2c8d6
@@ -10,14 +10,20 @@ require (
 	github.com/AlpacaLabs/protorepo-account-go v0.0.0-20200515160225-7d122739336d
 	github.com/AlpacaLabs/protorepo-pagination-go v0.0.0-20200503181518-cbf4b2f30657
 	github.com/badoux/checkmail v0.0.0-20181210160741-9661bd69e9ad
+	github.com/golang/protobuf v1.4.1
 	github.com/gorilla/mux v1.7.4
 	github.com/guregu/null v4.0.0+incompatible
+	github.com/jackc/pgconn v1.5.0
 	github.com/jackc/pgx/v4 v4.6.0
 	github.com/rs/xid v1.2.1
+	github.com/segmentio/kafka-go v0.3.6
 	github.com/sirupsen/logrus v1.6.0
 	github.com/spf13/pflag v1.0.5
 	github.com/spf13/viper v1.6.3
 	github.com/ttacon/builder v0.0.0-20170518171403-c099f663e1c2 // indirect
 	github.com/ttacon/libphonenumber v1.1.0
+	golang.org/x/net v0.0.0-20190813141303-74dc4d7220e7
+	golang.org/x/text v0.3.2
 	google.golang.org/grpc v1.29.1
+	google.golang.org/protobuf v1.22.0
 )
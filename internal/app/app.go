@@ -4,6 +4,7 @@ import (
 	"sync"
 
 	"github.com/AlpacaLabs/api-account/internal/async"
+	"github.com/AlpacaLabs/api-account/internal/auth"
 
 	"github.com/AlpacaLabs/api-account/internal/grpc"
 
@@ -11,6 +12,7 @@ import (
 	"github.com/AlpacaLabs/api-account/internal/db"
 	"github.com/AlpacaLabs/api-account/internal/http"
 	"github.com/AlpacaLabs/api-account/internal/service"
+	"github.com/AlpacaLabs/api-account/internal/sms"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -25,28 +27,62 @@ func NewApp(c configuration.Config) App {
 }
 
 func (a App) Run() {
-	dbConn, err := db.Connect(a.config.SQLConfig)
+	dbPool, err := db.Connect(a.config)
 	if err != nil {
 		log.Fatalf("failed to dial database: %v", err)
 	}
-	dbClient := db.NewClient(dbConn)
-	svc := service.NewService(a.config, dbClient)
+	defer dbPool.Close()
+
+	if a.config.AutoMigrate {
+		if err := migrateUp(dbPool); err != nil {
+			log.Fatalf("failed to migrate database: %v", err)
+		}
+	}
+
+	dbClient := db.NewClient(dbPool)
+	svc, err := service.NewService(a.config, dbClient)
+	if err != nil {
+		log.Fatalf("failed to create service: %v", err)
+	}
+
+	authenticator, err := auth.NewJWTAuthenticator(a.config.AuthConfig)
+	if err != nil {
+		log.Fatalf("failed to load JWT verification keys: %v", err)
+	}
+
+	smsProvider, err := sms.NewProvider(a.config)
+	if err != nil {
+		log.Fatalf("failed to create SMS provider: %v", err)
+	}
 
 	var wg sync.WaitGroup
 
 	wg.Add(1)
-	httpServer := http.NewServer(a.config, svc)
+	httpServer := http.NewServer(a.config, svc, authenticator)
 	go httpServer.Run()
 
 	wg.Add(1)
-	grpcServer := grpc.NewServer(a.config, svc)
+	grpcServer := grpc.NewServer(a.config, svc, authenticator)
 	go grpcServer.Run()
 
 	wg.Add(1)
-	go async.HandleConfirmEmailAddressRequest(a.config, svc)
+	go async.SendPhoneNumberVerificationCodes(a.config, smsProvider)
+
+	go async.NormalizeEmailAddresses(svc)
+	go async.NormalizePhoneNumbers(svc)
+	go async.NormalizeUsernames(svc)
+
+	wg.Add(1)
+	go async.PurgeDeletedAccounts(a.config, svc)
+
+	wg.Add(1)
+	go async.RelayOutbox(a.config, svc)
+
+	wg.Add(1)
+	go async.PurgeRelayedOutboxMessages(a.config, svc)
 
 	wg.Add(1)
-	go async.HandleConfirmPhoneNumberRequest(a.config, svc)
+	go async.RefreshEmailDomainPolicy(a.config, svc)
 
 	wg.Wait()
 }
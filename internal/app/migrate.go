@@ -0,0 +1,95 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/AlpacaLabs/api-account/internal/db"
+	"github.com/jackc/pgx/v4/pgxpool"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	MigrateUp     = "up"
+	MigrateDown   = "down"
+	MigrateStatus = "status"
+)
+
+// Migrate runs a migrate subcommand: up, down or status.
+func (a App) Migrate(command string) error {
+	dbPool, err := db.Connect(a.config)
+	if err != nil {
+		return fmt.Errorf("failed to dial database: %w", err)
+	}
+	defer dbPool.Close()
+
+	switch command {
+	case MigrateUp:
+		return migrateUp(dbPool)
+	case MigrateDown:
+		return migrateDown(dbPool)
+	case MigrateStatus:
+		return migrateStatus(dbPool)
+	default:
+		return fmt.Errorf("unknown migrate command %q; expected %s, %s or %s", command, MigrateUp, MigrateDown, MigrateStatus)
+	}
+}
+
+func migrateUp(dbPool *pgxpool.Pool) error {
+	m, err := db.NewMigrator(dbPool)
+	if err != nil {
+		return err
+	}
+
+	applied, err := m.Up(context.TODO())
+	for _, migration := range applied {
+		log.Infof("Applied migration %04d_%s", migration.Version, migration.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(applied) == 0 {
+		log.Info("Database schema is up to date")
+	}
+
+	return nil
+}
+
+func migrateDown(dbPool *pgxpool.Pool) error {
+	m, err := db.NewMigrator(dbPool)
+	if err != nil {
+		return err
+	}
+
+	reverted, err := m.Down(context.TODO())
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Reverted migration %04d_%s", reverted.Version, reverted.Name)
+
+	return nil
+}
+
+func migrateStatus(dbPool *pgxpool.Pool) error {
+	m, err := db.NewMigrator(dbPool)
+	if err != nil {
+		return err
+	}
+
+	statuses, err := m.Status(context.TODO())
+	if err != nil {
+		return err
+	}
+
+	for _, s := range statuses {
+		if s.AppliedAt == nil {
+			log.Infof("%04d_%s: pending", s.Version, s.Name)
+		} else {
+			log.Infof("%04d_%s: applied at %s", s.Version, s.Name, s.AppliedAt)
+		}
+	}
+
+	return nil
+}
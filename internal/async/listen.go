@@ -6,23 +6,16 @@ import (
 
 	"github.com/AlpacaLabs/api-account/internal/configuration"
 	"github.com/AlpacaLabs/api-account/internal/service"
+	"github.com/AlpacaLabs/api-account/internal/sms"
 	accountV1 "github.com/AlpacaLabs/protorepo-account-go/alpacalabs/account/v1"
 
 	goKafka "github.com/AlpacaLabs/go-kafka"
 	log "github.com/sirupsen/logrus"
 )
 
-const (
-	TopicForConfirmEmailAddressRequest = "confirm-email-address-request"
-	TopicForConfirmPhoneNumberRequest  = "confirm-phone-number-request"
-)
-
-func HandleConfirmEmailAddressRequest(config configuration.Config, s service.Service) {
-	handle(TopicForConfirmEmailAddressRequest, config, handleConfirmEmailAddressRequest(s))
-}
-
-func HandleConfirmPhoneNumberRequest(config configuration.Config, s service.Service) {
-	handle(TopicForConfirmPhoneNumberRequest, config, handleConfirmPhoneNumberRequest(s))
+// SendPhoneNumberVerificationCodes texts verification codes out as they're requested.
+func SendPhoneNumberVerificationCodes(config configuration.Config, provider sms.Provider) {
+	handle(service.TopicForPhoneNumberConfirmationRequested, config, handlePhoneNumberConfirmationRequested(provider))
 }
 
 func handle(topic string, config configuration.Config, fn goKafka.ProcessFunc) {
@@ -44,30 +37,24 @@ func handle(topic string, config configuration.Config, fn goKafka.ProcessFunc) {
 	}
 }
 
-func handleConfirmEmailAddressRequest(s service.Service) goKafka.ProcessFunc {
+func handlePhoneNumberConfirmationRequested(provider sms.Provider) goKafka.ProcessFunc {
 	return func(ctx context.Context, message goKafka.Message) {
 		// Convert kafka.Message to Protocol Buffer
-		pb := &accountV1.ConfirmEmailAddressRequest{}
+		pb := &accountV1.PhoneNumber{}
 		if err := message.Unmarshal(pb); err != nil {
 			log.Errorf("failed to unmarshal protobuf from kafka message: %v", err)
+			return
 		}
 
-		if err := s.ConfirmEmailAddress(ctx, pb); err != nil {
-			log.Errorf("failed to process kafka message in transaction: %v", err)
-		}
-	}
-}
-
-func handleConfirmPhoneNumberRequest(s service.Service) goKafka.ProcessFunc {
-	return func(ctx context.Context, message goKafka.Message) {
-		// Convert kafka.Message to Protocol Buffer
-		pb := &accountV1.ConfirmPhoneNumberRequest{}
-		if err := message.Unmarshal(pb); err != nil {
-			log.Errorf("failed to unmarshal protobuf from kafka message: %v", err)
+		code, err := message.GetString(service.HeaderForVerificationCode)
+		if err != nil {
+			log.Errorf("kafka message for phone number %s has no verification code: %v", pb.Id, err)
+			return
 		}
 
-		if err := s.ConfirmPhoneNumber(ctx, pb); err != nil {
-			log.Errorf("failed to process kafka message in transaction: %v", err)
+		body := fmt.Sprintf("Your verification code is %s", code)
+		if err := provider.Send(ctx, pb.PhoneNumber, body); err != nil {
+			log.Errorf("failed to send verification code to phone number %s: %v", pb.Id, err)
 		}
 	}
 }
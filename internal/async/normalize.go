@@ -0,0 +1,49 @@
+package async
+
+import (
+	"context"
+
+	"github.com/AlpacaLabs/api-account/internal/service"
+	log "github.com/sirupsen/logrus"
+)
+
+// NormalizePhoneNumbers converts phone numbers stored before E.164
+// normalization. It returns once they're all done.
+func NormalizePhoneNumbers(s service.Service) {
+	n, conflicts, err := s.NormalizePhoneNumbers(context.TODO())
+	for _, c := range conflicts {
+		log.Errorf("phone number %s of account %s can't be stored as %s: phone number %s of account %s already is",
+			c.PhoneNumberID, c.AccountID, c.E164PhoneNumber, c.ConflictingPhoneNumberID, c.ConflictingAccountID)
+	}
+	if err != nil {
+		log.Errorf("failed to normalize phone numbers: %v", err)
+	} else if n > 0 {
+		log.Infof("normalized %d phone numbers", n)
+	}
+}
+
+// NormalizeUsernames stores skeletons and folded forms for usernames set
+// before they were stored. It returns once they're all done.
+func NormalizeUsernames(s service.Service) {
+	n, err := s.NormalizeUsernames(context.TODO())
+	if err != nil {
+		log.Errorf("failed to normalize usernames: %v", err)
+	} else if n > 0 {
+		log.Infof("normalized %d usernames", n)
+	}
+}
+
+// NormalizeEmailAddresses works out the canonical form of email addresses
+// stored under other rules. It returns once they're all done.
+func NormalizeEmailAddresses(s service.Service) {
+	n, conflicts, err := s.NormalizeEmailAddresses(context.TODO())
+	for _, c := range conflicts {
+		log.Errorf("email address %s of account %s can't be canonicalized as %s: email address %s of account %s already is",
+			c.EmailAddressID, c.AccountID, c.CanonicalEmailAddress, c.ConflictingEmailAddressID, c.ConflictingAccountID)
+	}
+	if err != nil {
+		log.Errorf("failed to normalize email addresses: %v", err)
+	} else if n > 0 {
+		log.Infof("normalized %d email addresses", n)
+	}
+}
@@ -0,0 +1,50 @@
+package async
+
+import (
+	"context"
+	"time"
+
+	"github.com/AlpacaLabs/api-account/internal/configuration"
+	"github.com/AlpacaLabs/api-account/internal/service"
+	log "github.com/sirupsen/logrus"
+)
+
+// PurgeDeletedAccounts periodically hard-deletes accounts whose deletion
+// grace period has elapsed. It blocks forever.
+func PurgeDeletedAccounts(config configuration.Config, s service.Service) {
+	ctx := context.TODO()
+
+	ticker := time.NewTicker(config.AccountPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		n, err := s.PurgeDeletedAccounts(ctx)
+		if err != nil {
+			log.Errorf("failed to purge deleted accounts: %v", err)
+		} else if n > 0 {
+			log.Infof("purged %d deleted accounts", n)
+		}
+
+		<-ticker.C
+	}
+}
+
+// PurgeRelayedOutboxMessages periodically deletes relayed outbox messages
+// past their retention period. It blocks forever.
+func PurgeRelayedOutboxMessages(config configuration.Config, s service.Service) {
+	ctx := context.TODO()
+
+	ticker := time.NewTicker(config.OutboxPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		n, err := s.PurgeRelayedOutboxMessages(ctx)
+		if err != nil {
+			log.Errorf("failed to purge relayed outbox messages: %v", err)
+		} else if n > 0 {
+			log.Infof("purged %d relayed outbox messages", n)
+		}
+
+		<-ticker.C
+	}
+}
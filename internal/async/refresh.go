@@ -0,0 +1,29 @@
+package async
+
+import (
+	"context"
+	"time"
+
+	"github.com/AlpacaLabs/api-account/internal/configuration"
+	"github.com/AlpacaLabs/api-account/internal/service"
+	log "github.com/sirupsen/logrus"
+)
+
+// RefreshEmailDomainPolicy periodically reloads the admin-managed email
+// domain rules and the disposable domains file, so changes made through
+// other instances or to the file take effect without a restart.
+// It blocks forever.
+func RefreshEmailDomainPolicy(config configuration.Config, s service.Service) {
+	ctx := context.TODO()
+
+	ticker := time.NewTicker(config.EmailDomainPolicyRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.RefreshEmailDomainPolicy(ctx); err != nil {
+			log.Errorf("failed to refresh email domain policy: %v", err)
+		}
+
+		<-ticker.C
+	}
+}
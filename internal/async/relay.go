@@ -0,0 +1,90 @@
+package async
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/AlpacaLabs/api-account/internal/configuration"
+	"github.com/AlpacaLabs/api-account/internal/db/entities"
+	"github.com/AlpacaLabs/api-account/internal/service"
+	"github.com/segmentio/kafka-go"
+	log "github.com/sirupsen/logrus"
+)
+
+// RelayOutbox polls the transactional outbox and publishes pending
+// messages to Kafka. It blocks forever.
+func RelayOutbox(config configuration.Config, s service.Service) {
+	ctx := context.TODO()
+
+	publisher := newKafkaPublisher(config)
+
+	for {
+		n, err := s.RelayOutboxMessages(ctx, publisher)
+		if err != nil {
+			log.Errorf("failed to relay outbox messages: %v", err)
+		}
+
+		// Keep draining while there's work to do
+		if n == 0 {
+			time.Sleep(config.OutboxPollInterval)
+		}
+	}
+}
+
+// kafkaPublisher publishes outbox messages to the Kafka topic they name.
+type kafkaPublisher struct {
+	brokers []string
+
+	mu      sync.Mutex
+	writers map[string]*kafka.Writer
+}
+
+func newKafkaPublisher(config configuration.Config) *kafkaPublisher {
+	return &kafkaPublisher{
+		brokers: []string{
+			fmt.Sprintf("%s:%d", config.KafkaConfig.Host, config.KafkaConfig.Port),
+		},
+		writers: make(map[string]*kafka.Writer),
+	}
+}
+
+func (p *kafkaPublisher) Publish(ctx context.Context, m entities.OutboxMessage) error {
+	headers := make([]kafka.Header, 0, len(m.Headers))
+	for k, v := range m.Headers {
+		headers = append(headers, kafka.Header{Key: k, Value: []byte(v)})
+	}
+
+	return p.writer(m.Topic).WriteMessages(ctx, kafka.Message{
+		Key:     []byte(m.Key),
+		Value:   m.Payload,
+		Headers: headers,
+		Time:    m.CreatedAt,
+	})
+}
+
+func (p *kafkaPublisher) writer(topic string) *kafka.Writer {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if w, ok := p.writers[topic]; ok {
+		return w
+	}
+
+	w := kafka.NewWriter(kafka.WriterConfig{
+		Brokers: p.brokers,
+		Topic:   topic,
+
+		// Messages with the same key land on the same partition,
+		// which preserves their relative order.
+		Balancer: &kafka.Hash{},
+
+		// The relay publishes one message at a time and waits for it to be
+		// acknowledged, so there's no point waiting for a batch to fill up.
+		BatchSize: 1,
+	})
+	p.writers[topic] = w
+
+	return w
+}
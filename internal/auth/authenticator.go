@@ -0,0 +1,27 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+var (
+	ErrMalformedAuthorization = errors.New("authorization header must use the Bearer scheme")
+	ErrInvalidToken           = errors.New("bearer token is invalid")
+	ErrExpiredToken           = errors.New("bearer token has expired")
+)
+
+// Authenticator turns a raw bearer token into a Principal.
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (Principal, error)
+}
+
+// ParseBearerToken extracts the token from an Authorization header value.
+func ParseBearerToken(header string) (string, error) {
+	const prefix = "bearer "
+	if len(header) <= len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return "", ErrMalformedAuthorization
+	}
+	return strings.TrimSpace(header[len(prefix):]), nil
+}
@@ -0,0 +1,225 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/AlpacaLabs/api-account/internal/configuration"
+)
+
+const (
+	algHS256 = "HS256"
+	algRS256 = "RS256"
+)
+
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+}
+
+type jwtClaims struct {
+	Subject   string   `json:"sub"`
+	Issuer    string   `json:"iss"`
+	Audience  audience `json:"aud"`
+	ExpiresAt int64    `json:"exp"`
+	NotBefore int64    `json:"nbf"`
+	ID        string   `json:"jti"`
+	Roles     []string `json:"roles"`
+	Scope     string   `json:"scope"`
+}
+
+// audience is the JWT "aud" claim, which may be a string or an array of strings.
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*a = audience{s}
+		return nil
+	}
+	var arr []string
+	if err := json.Unmarshal(b, &arr); err != nil {
+		return err
+	}
+	*a = arr
+	return nil
+}
+
+func (a audience) contains(s string) bool {
+	for _, v := range a {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// JWTAuthenticator verifies HS256 and RS256 signed JSON Web Tokens.
+type JWTAuthenticator struct {
+	hmacKey      []byte
+	rsaPublicKey *rsa.PublicKey
+	issuer       string
+	audience     string
+	now          func() time.Time
+}
+
+// NewJWTAuthenticator loads the signing keys named in the config from disk.
+// At least one of the HMAC key file or RSA public key file must be set.
+func NewJWTAuthenticator(config configuration.AuthConfig) (*JWTAuthenticator, error) {
+	a := &JWTAuthenticator{
+		issuer:   config.Issuer,
+		audience: config.Audience,
+		now:      time.Now,
+	}
+
+	if config.HMACKeyFile != "" {
+		b, err := ioutil.ReadFile(config.HMACKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read HMAC key file: %w", err)
+		}
+		a.hmacKey = bytes.TrimSpace(b)
+	}
+
+	if config.RSAPublicKeyFile != "" {
+		b, err := ioutil.ReadFile(config.RSAPublicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read RSA public key file: %w", err)
+		}
+		key, err := parseRSAPublicKey(b)
+		if err != nil {
+			return nil, err
+		}
+		a.rsaPublicKey = key
+	}
+
+	if a.hmacKey == nil && a.rsaPublicKey == nil {
+		return nil, errors.New("no JWT verification keys configured")
+	}
+
+	return a, nil
+}
+
+func (a *JWTAuthenticator) Authenticate(ctx context.Context, token string) (Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Principal{}, ErrInvalidToken
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return Principal{}, ErrInvalidToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Principal{}, ErrInvalidToken
+	}
+
+	if err := a.verifySignature(header.Algorithm, parts[0]+"."+parts[1], signature); err != nil {
+		return Principal{}, err
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return Principal{}, ErrInvalidToken
+	}
+
+	now := a.now().Unix()
+	if claims.ExpiresAt == 0 || now >= claims.ExpiresAt {
+		return Principal{}, ErrExpiredToken
+	}
+	if claims.NotBefore != 0 && now < claims.NotBefore {
+		return Principal{}, ErrInvalidToken
+	}
+	if a.issuer != "" && claims.Issuer != a.issuer {
+		return Principal{}, ErrInvalidToken
+	}
+	if a.audience != "" && !claims.Audience.contains(a.audience) {
+		return Principal{}, ErrInvalidToken
+	}
+	if claims.Subject == "" {
+		return Principal{}, ErrInvalidToken
+	}
+
+	roles := make([]Role, 0, len(claims.Roles))
+	for _, r := range claims.Roles {
+		roles = append(roles, Role(r))
+	}
+
+	return Principal{
+		AccountID: claims.Subject,
+		Roles:     roles,
+		Scopes:    strings.Fields(claims.Scope),
+		TokenID:   claims.ID,
+	}, nil
+}
+
+func (a *JWTAuthenticator) verifySignature(alg, signingInput string, signature []byte) error {
+	digest := sha256.Sum256([]byte(signingInput))
+
+	switch alg {
+	case algHS256:
+		if a.hmacKey == nil {
+			return ErrInvalidToken
+		}
+		mac := hmac.New(sha256.New, a.hmacKey)
+		mac.Write([]byte(signingInput))
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return ErrInvalidToken
+		}
+	case algRS256:
+		if a.rsaPublicKey == nil {
+			return ErrInvalidToken
+		}
+		if err := rsa.VerifyPKCS1v15(a.rsaPublicKey, crypto.SHA256, digest[:], signature); err != nil {
+			return ErrInvalidToken
+		}
+	default:
+		// Notably, this rejects "none".
+		return ErrInvalidToken
+	}
+
+	return nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+func parseRSAPublicKey(b []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.New("RSA public key file is not PEM encoded")
+	}
+
+	if key, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return nil, errors.New("public key is not an RSA key")
+		}
+		return rsaKey, nil
+	}
+
+	key, err := x509.ParsePKCS1PublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse RSA public key: %w", err)
+	}
+	return key, nil
+}
@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// RoleAdmin grants full read and write access to every account,
+	// including unmasked contact details.
+	RoleAdmin = Role("admin")
+
+	// RoleSupport grants read access to every account, with contact
+	// details masked.
+	RoleSupport = Role("support")
+
+	// RoleSelf is implicitly held by every authenticated principal and
+	// grants access to the resources the principal owns. Ownership itself
+	// is checked by the service layer.
+	RoleSelf = Role("self")
+)
+
+const (
+	ScopeAccountsRead  = "accounts:read"
+	ScopeAccountsWrite = "accounts:write"
+)
+
+// Policy describes what a caller needs in order to perform an operation.
+type Policy struct {
+	// Public policies may be satisfied by anonymous callers.
+	Public bool
+
+	// AnyRole, if non-empty, requires the principal to hold at least one of these roles.
+	AnyRole []Role
+
+	// Scopes must all be present on the principal's token.
+	Scopes []string
+}
+
+// PermissionError is returned when a principal does not satisfy a policy.
+type PermissionError struct {
+	// Missing names the permission the principal lacks.
+	Missing string
+}
+
+func (e PermissionError) Error() string {
+	return fmt.Sprintf("missing permission: %s", e.Missing)
+}
+
+// Authorize checks the principal against the policy. Callers are expected
+// to have handled the anonymous case (Public policies) themselves.
+func (p Policy) Authorize(principal Principal) error {
+	if len(p.AnyRole) > 0 && !principal.hasAnyRole(p.AnyRole) {
+		names := make([]string, 0, len(p.AnyRole))
+		for _, r := range p.AnyRole {
+			names = append(names, string(r))
+		}
+		return PermissionError{Missing: "role " + strings.Join(names, " or ")}
+	}
+
+	for _, scope := range p.Scopes {
+		if !principal.HasScope(scope) {
+			return PermissionError{Missing: "scope " + scope}
+		}
+	}
+
+	return nil
+}
+
+func (p Principal) hasAnyRole(roles []Role) bool {
+	for _, r := range roles {
+		if r == RoleSelf && p.AccountID != "" {
+			return true
+		}
+		if p.HasRole(r) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"context"
+)
+
+// Role is a coarse-grained permission granted to a principal.
+type Role string
+
+// Principal is the authenticated identity behind a request.
+type Principal struct {
+	// AccountID is the ID of the account the bearer token was issued to.
+	AccountID string
+
+	// Roles are the roles granted to the account.
+	Roles []Role
+
+	// Scopes are the OAuth-style scopes the token was issued with.
+	Scopes []string
+
+	// TokenID uniquely identifies the token the principal was built from.
+	TokenID string
+}
+
+// HasRole reports whether the principal was granted the given role.
+func (p Principal) HasRole(role Role) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// HasScope reports whether the principal's token carries the given scope.
+func (p Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+// NewContext returns a copy of ctx that carries the principal.
+func NewContext(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal stored in ctx, if any.
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
@@ -2,6 +2,7 @@ package configuration
 
 import (
 	"encoding/json"
+	"time"
 
 	configuration "github.com/AlpacaLabs/go-config"
 	"github.com/rs/xid"
@@ -15,6 +16,44 @@ import (
 const (
 	flagForGrpcPort = "grpc_port"
 	flagForHTTPPort = "http_port"
+
+	flagForDBMinConns          = "db_min_conns"
+	flagForDBMaxConns          = "db_max_conns"
+	flagForDBMaxConnLifetime   = "db_max_conn_lifetime"
+	flagForDBHealthCheckPeriod = "db_health_check_period"
+	flagForDBStatementTimeout  = "db_statement_timeout"
+	flagForDBAutoMigrate       = "db_auto_migrate"
+
+	flagForJWTHMACKeyFile      = "jwt_hmac_key_file"
+	flagForJWTRSAPublicKeyFile = "jwt_rsa_public_key_file"
+	flagForJWTIssuer           = "jwt_issuer"
+	flagForJWTAudience         = "jwt_audience"
+
+	flagForAccountDeletionGracePeriod = "account_deletion_grace_period"
+	flagForAccountPurgeInterval       = "account_purge_interval"
+
+	flagForOutboxPollInterval  = "outbox_poll_interval"
+	flagForOutboxRetention     = "outbox_retention"
+	flagForOutboxPurgeInterval = "outbox_purge_interval"
+
+	flagForSigningKeyFile            = "signing_key_file"
+	flagForEmailConfirmationTokenTTL = "email_confirmation_token_ttl"
+
+	flagForPhoneVerificationCodeTTL     = "phone_verification_code_ttl"
+	flagForPhoneVerificationMaxAttempts = "phone_verification_max_attempts"
+	flagForPhoneVerificationLockout     = "phone_verification_lockout"
+	flagForPhoneVerificationResendDelay = "phone_verification_resend_delay"
+	flagForSMSProvider                  = "sms_provider"
+	flagForDefaultPhoneRegion           = "default_phone_region"
+
+	flagForSignupRequiredIdentifiers = "signup_required_identifiers"
+
+	flagForEmailProviderRules               = "email_provider_rules"
+	flagForEmailDomainPolicyRefreshInterval = "email_domain_policy_refresh_interval"
+	flagForEmailDisposableDomainsFile       = "email_disposable_domains_file"
+
+	flagForUsernameChangeCooldown = "username_change_cooldown"
+	flagForUsernameHoldPeriod     = "username_hold_period"
 )
 
 type Config struct {
@@ -30,11 +69,141 @@ type Config struct {
 	// SQLConfig provides configuration for connecting to a SQL database.
 	SQLConfig configuration.SQLConfig
 
+	// SQLPoolConfig controls the pool of database connections.
+	SQLPoolConfig SQLPoolConfig
+
+	// AutoMigrate applies any pending schema migrations on startup.
+	AutoMigrate bool
+
 	// GrpcPort controls what port our gRPC server runs on.
 	GrpcPort int
 
 	// HTTPPort controls what port our HTTP server runs on.
 	HTTPPort int
+
+	// AuthConfig provides configuration for verifying bearer tokens.
+	AuthConfig AuthConfig
+
+	// AccountDeletionGracePeriod is how long a deleted account can still be
+	// restored before it becomes eligible for purging.
+	AccountDeletionGracePeriod time.Duration
+
+	// AccountPurgeInterval controls how often deleted accounts past their
+	// grace period are purged.
+	AccountPurgeInterval time.Duration
+
+	// OutboxPollInterval controls how often the transactional outbox is
+	// polled for messages to relay to Kafka when it's idle.
+	OutboxPollInterval time.Duration
+
+	// OutboxRetention is how long messages are kept in the outbox after
+	// they've been relayed, which helps when debugging delivery.
+	OutboxRetention time.Duration
+
+	// OutboxPurgeInterval controls how often relayed messages past their
+	// retention are deleted from the outbox.
+	OutboxPurgeInterval time.Duration
+
+	// SigningKeyFile is the path to the secret this service uses to sign
+	// the tokens it hands out, such as email confirmation tokens.
+	SigningKeyFile string
+
+	// EmailConfirmationTokenTTL is how long an email confirmation token
+	// stays valid after it is sent.
+	EmailConfirmationTokenTTL time.Duration
+
+	// PhoneVerificationCodeTTL is how long an SMS verification code stays
+	// valid after it is sent.
+	PhoneVerificationCodeTTL time.Duration
+
+	// PhoneVerificationMaxAttempts is how many wrong codes can be entered
+	// before a phone number is locked out. Sending a new code doesn't reset
+	// the count, so it can't be used to get more guesses.
+	PhoneVerificationMaxAttempts int
+
+	// PhoneVerificationLockout is how long a phone number stays locked out
+	// after its last wrong code, before codes can be entered again.
+	PhoneVerificationLockout time.Duration
+
+	// PhoneVerificationResendDelay is how long after a code is sent
+	// before another one can be sent to the same phone number.
+	PhoneVerificationResendDelay time.Duration
+
+	// SMSProvider selects how text messages are sent.
+	// Only "log", which just logs messages, is supported for now.
+	SMSProvider string
+
+	// DefaultPhoneRegion is the two-letter region, like "US", that phone
+	// numbers written without a country code are read as belonging to.
+	// Requests can override it.
+	DefaultPhoneRegion string
+
+	// SignupRequiredIdentifiers lists which of "username", "email_address"
+	// and "phone_number" must be given to create an account. The others
+	// are optional, but every account needs at least one of them.
+	SignupRequiredIdentifiers []string
+
+	// EmailProviderRules applies mail providers' own address rules when
+	// working out which addresses share an inbox, e.g. that Gmail ignores
+	// dots and +tags. Addresses already registered are brought in line in
+	// the background when the service starts; ones that would then share
+	// an inbox with another address are logged and recorded in
+	// email_address_conflict, and keep their old canonical form.
+	EmailProviderRules bool
+
+	// EmailDomainPolicyRefreshInterval controls how often the admin-managed
+	// email domain rules are reloaded from the database, and the disposable
+	// domains from EmailDisposableDomainsFile.
+	EmailDomainPolicyRefreshInterval time.Duration
+
+	// EmailDisposableDomainsFile is a file listing disposable email domains,
+	// one per line, which replaces the list built into the binary. It's
+	// reloaded while the service runs, so it can be kept up to date without
+	// a release. If it's empty, the built-in list is used, and only changes
+	// with a new release.
+	EmailDisposableDomainsFile string
+
+	// UsernameChangeCooldown is how long an account has to wait after
+	// changing its username before it can change it again.
+	UsernameChangeCooldown time.Duration
+
+	// UsernameHoldPeriod is how long a username given up by one account is
+	// kept from being claimed by another, so no one can impersonate the
+	// old owner. Lookups by the old username find the renamed account
+	// until it ends.
+	UsernameHoldPeriod time.Duration
+}
+
+type SQLPoolConfig struct {
+	// MinConns is how many connections the pool keeps open when idle.
+	MinConns int
+
+	// MaxConns caps how many connections the pool opens.
+	MaxConns int
+
+	// MaxConnLifetime is how long a connection is used before it's replaced.
+	MaxConnLifetime time.Duration
+
+	// HealthCheckPeriod is how often idle connections are checked.
+	HealthCheckPeriod time.Duration
+
+	// StatementTimeout aborts any statement that runs for longer.
+	// Zero disables the timeout.
+	StatementTimeout time.Duration
+}
+
+type AuthConfig struct {
+	// HMACKeyFile is the path to the shared secret used to verify HS256 tokens.
+	HMACKeyFile string
+
+	// RSAPublicKeyFile is the path to a PEM encoded public key used to verify RS256 tokens.
+	RSAPublicKeyFile string
+
+	// Issuer, if set, must match the "iss" claim of every token.
+	Issuer string
+
+	// Audience, if set, must be present in the "aud" claim of every token.
+	Audience string
 }
 
 func (c Config) String() string {
@@ -51,6 +220,35 @@ func LoadConfig() Config {
 		AppID:    xid.New().String(),
 		GrpcPort: 8081,
 		HTTPPort: 8083,
+
+		SQLPoolConfig: SQLPoolConfig{
+			MinConns:          2,
+			MaxConns:          20,
+			MaxConnLifetime:   time.Hour,
+			HealthCheckPeriod: time.Minute,
+			StatementTimeout:  30 * time.Second,
+		},
+
+		AccountDeletionGracePeriod: 30 * 24 * time.Hour,
+		AccountPurgeInterval:       time.Hour,
+		OutboxPollInterval:         time.Second,
+		OutboxRetention:            7 * 24 * time.Hour,
+		OutboxPurgeInterval:        time.Hour,
+		EmailConfirmationTokenTTL:  24 * time.Hour,
+
+		PhoneVerificationCodeTTL:     10 * time.Minute,
+		PhoneVerificationMaxAttempts: 5,
+		PhoneVerificationLockout:     time.Hour,
+		PhoneVerificationResendDelay: time.Minute,
+		SMSProvider:                  "log",
+		DefaultPhoneRegion:           "US",
+
+		SignupRequiredIdentifiers: []string{"email_address"},
+
+		EmailDomainPolicyRefreshInterval: time.Minute,
+
+		UsernameChangeCooldown: 30 * 24 * time.Hour,
+		UsernameHoldPeriod:     90 * 24 * time.Hour,
 	}
 
 	c.KafkaConfig = configuration.LoadKafkaConfig()
@@ -58,16 +256,103 @@ func LoadConfig() Config {
 
 	flag.Int(flagForGrpcPort, c.GrpcPort, "gRPC port")
 	flag.Int(flagForHTTPPort, c.HTTPPort, "HTTP port")
+	flag.Int(flagForDBMinConns, c.SQLPoolConfig.MinConns, "Minimum number of database connections")
+	flag.Int(flagForDBMaxConns, c.SQLPoolConfig.MaxConns, "Maximum number of database connections")
+	flag.Duration(flagForDBMaxConnLifetime, c.SQLPoolConfig.MaxConnLifetime, "How long a database connection is used before it's replaced")
+	flag.Duration(flagForDBHealthCheckPeriod, c.SQLPoolConfig.HealthCheckPeriod, "How often idle database connections are checked")
+	flag.Duration(flagForDBStatementTimeout, c.SQLPoolConfig.StatementTimeout, "How long a SQL statement may run before it's aborted")
+	flag.Bool(flagForDBAutoMigrate, c.AutoMigrate, "Apply pending schema migrations on startup")
+	flag.String(flagForJWTHMACKeyFile, c.AuthConfig.HMACKeyFile, "Path to HS256 JWT verification secret")
+	flag.String(flagForJWTRSAPublicKeyFile, c.AuthConfig.RSAPublicKeyFile, "Path to RS256 JWT verification public key")
+	flag.String(flagForJWTIssuer, c.AuthConfig.Issuer, "Required JWT issuer")
+	flag.String(flagForJWTAudience, c.AuthConfig.Audience, "Required JWT audience")
+	flag.Duration(flagForAccountDeletionGracePeriod, c.AccountDeletionGracePeriod, "How long deleted accounts can be restored")
+	flag.Duration(flagForAccountPurgeInterval, c.AccountPurgeInterval, "How often deleted accounts are purged")
+	flag.Duration(flagForOutboxPollInterval, c.OutboxPollInterval, "How often the outbox is polled when idle")
+	flag.Duration(flagForOutboxRetention, c.OutboxRetention, "How long relayed outbox messages are kept")
+	flag.Duration(flagForOutboxPurgeInterval, c.OutboxPurgeInterval, "How often relayed outbox messages are purged")
+	flag.String(flagForSigningKeyFile, c.SigningKeyFile, "Path to the secret used to sign tokens")
+	flag.Duration(flagForEmailConfirmationTokenTTL, c.EmailConfirmationTokenTTL, "How long email confirmation tokens are valid")
+	flag.Duration(flagForPhoneVerificationCodeTTL, c.PhoneVerificationCodeTTL, "How long SMS verification codes are valid")
+	flag.Int(flagForPhoneVerificationMaxAttempts, c.PhoneVerificationMaxAttempts, "Wrong SMS verification codes allowed before lockout")
+	flag.Duration(flagForPhoneVerificationLockout, c.PhoneVerificationLockout, "How long a phone number stays locked out after too many wrong codes")
+	flag.Duration(flagForPhoneVerificationResendDelay, c.PhoneVerificationResendDelay, "How long to wait before sending another SMS verification code")
+	flag.String(flagForSMSProvider, c.SMSProvider, "How text messages are sent")
+	flag.String(flagForDefaultPhoneRegion, c.DefaultPhoneRegion, "Region of phone numbers written without a country code")
+	flag.StringSlice(flagForSignupRequiredIdentifiers, c.SignupRequiredIdentifiers, "Identifiers that must be given to create an account")
+	flag.Bool(flagForEmailProviderRules, c.EmailProviderRules, "Apply provider-specific rules, like Gmail's, to canonical email addresses")
+	flag.Duration(flagForEmailDomainPolicyRefreshInterval, c.EmailDomainPolicyRefreshInterval, "How often email domain rules are reloaded")
+	flag.String(flagForEmailDisposableDomainsFile, c.EmailDisposableDomainsFile, "File listing disposable email domains, replacing the built-in list")
+	flag.Duration(flagForUsernameChangeCooldown, c.UsernameChangeCooldown, "How long an account must wait between username changes")
+	flag.Duration(flagForUsernameHoldPeriod, c.UsernameHoldPeriod, "How long a released username is kept from other accounts")
 
 	flag.Parse()
 
 	viper.BindPFlag(flagForGrpcPort, flag.Lookup(flagForGrpcPort))
 	viper.BindPFlag(flagForHTTPPort, flag.Lookup(flagForHTTPPort))
+	viper.BindPFlag(flagForDBMinConns, flag.Lookup(flagForDBMinConns))
+	viper.BindPFlag(flagForDBMaxConns, flag.Lookup(flagForDBMaxConns))
+	viper.BindPFlag(flagForDBMaxConnLifetime, flag.Lookup(flagForDBMaxConnLifetime))
+	viper.BindPFlag(flagForDBHealthCheckPeriod, flag.Lookup(flagForDBHealthCheckPeriod))
+	viper.BindPFlag(flagForDBStatementTimeout, flag.Lookup(flagForDBStatementTimeout))
+	viper.BindPFlag(flagForDBAutoMigrate, flag.Lookup(flagForDBAutoMigrate))
+	viper.BindPFlag(flagForJWTHMACKeyFile, flag.Lookup(flagForJWTHMACKeyFile))
+	viper.BindPFlag(flagForJWTRSAPublicKeyFile, flag.Lookup(flagForJWTRSAPublicKeyFile))
+	viper.BindPFlag(flagForJWTIssuer, flag.Lookup(flagForJWTIssuer))
+	viper.BindPFlag(flagForJWTAudience, flag.Lookup(flagForJWTAudience))
+	viper.BindPFlag(flagForAccountDeletionGracePeriod, flag.Lookup(flagForAccountDeletionGracePeriod))
+	viper.BindPFlag(flagForAccountPurgeInterval, flag.Lookup(flagForAccountPurgeInterval))
+	viper.BindPFlag(flagForOutboxPollInterval, flag.Lookup(flagForOutboxPollInterval))
+	viper.BindPFlag(flagForOutboxRetention, flag.Lookup(flagForOutboxRetention))
+	viper.BindPFlag(flagForOutboxPurgeInterval, flag.Lookup(flagForOutboxPurgeInterval))
+	viper.BindPFlag(flagForSigningKeyFile, flag.Lookup(flagForSigningKeyFile))
+	viper.BindPFlag(flagForEmailConfirmationTokenTTL, flag.Lookup(flagForEmailConfirmationTokenTTL))
+	viper.BindPFlag(flagForPhoneVerificationCodeTTL, flag.Lookup(flagForPhoneVerificationCodeTTL))
+	viper.BindPFlag(flagForPhoneVerificationMaxAttempts, flag.Lookup(flagForPhoneVerificationMaxAttempts))
+	viper.BindPFlag(flagForPhoneVerificationLockout, flag.Lookup(flagForPhoneVerificationLockout))
+	viper.BindPFlag(flagForPhoneVerificationResendDelay, flag.Lookup(flagForPhoneVerificationResendDelay))
+	viper.BindPFlag(flagForSMSProvider, flag.Lookup(flagForSMSProvider))
+	viper.BindPFlag(flagForDefaultPhoneRegion, flag.Lookup(flagForDefaultPhoneRegion))
+	viper.BindPFlag(flagForSignupRequiredIdentifiers, flag.Lookup(flagForSignupRequiredIdentifiers))
+	viper.BindPFlag(flagForEmailProviderRules, flag.Lookup(flagForEmailProviderRules))
+	viper.BindPFlag(flagForEmailDomainPolicyRefreshInterval, flag.Lookup(flagForEmailDomainPolicyRefreshInterval))
+	viper.BindPFlag(flagForEmailDisposableDomainsFile, flag.Lookup(flagForEmailDisposableDomainsFile))
+	viper.BindPFlag(flagForUsernameChangeCooldown, flag.Lookup(flagForUsernameChangeCooldown))
+	viper.BindPFlag(flagForUsernameHoldPeriod, flag.Lookup(flagForUsernameHoldPeriod))
 
 	viper.AutomaticEnv()
 
 	c.GrpcPort = viper.GetInt(flagForGrpcPort)
 	c.HTTPPort = viper.GetInt(flagForHTTPPort)
+	c.SQLPoolConfig.MinConns = viper.GetInt(flagForDBMinConns)
+	c.SQLPoolConfig.MaxConns = viper.GetInt(flagForDBMaxConns)
+	c.SQLPoolConfig.MaxConnLifetime = viper.GetDuration(flagForDBMaxConnLifetime)
+	c.SQLPoolConfig.HealthCheckPeriod = viper.GetDuration(flagForDBHealthCheckPeriod)
+	c.SQLPoolConfig.StatementTimeout = viper.GetDuration(flagForDBStatementTimeout)
+	c.AutoMigrate = viper.GetBool(flagForDBAutoMigrate)
+	c.AuthConfig.HMACKeyFile = viper.GetString(flagForJWTHMACKeyFile)
+	c.AuthConfig.RSAPublicKeyFile = viper.GetString(flagForJWTRSAPublicKeyFile)
+	c.AuthConfig.Issuer = viper.GetString(flagForJWTIssuer)
+	c.AuthConfig.Audience = viper.GetString(flagForJWTAudience)
+	c.AccountDeletionGracePeriod = viper.GetDuration(flagForAccountDeletionGracePeriod)
+	c.AccountPurgeInterval = viper.GetDuration(flagForAccountPurgeInterval)
+	c.OutboxPollInterval = viper.GetDuration(flagForOutboxPollInterval)
+	c.OutboxRetention = viper.GetDuration(flagForOutboxRetention)
+	c.OutboxPurgeInterval = viper.GetDuration(flagForOutboxPurgeInterval)
+	c.SigningKeyFile = viper.GetString(flagForSigningKeyFile)
+	c.EmailConfirmationTokenTTL = viper.GetDuration(flagForEmailConfirmationTokenTTL)
+	c.PhoneVerificationCodeTTL = viper.GetDuration(flagForPhoneVerificationCodeTTL)
+	c.PhoneVerificationMaxAttempts = viper.GetInt(flagForPhoneVerificationMaxAttempts)
+	c.PhoneVerificationLockout = viper.GetDuration(flagForPhoneVerificationLockout)
+	c.PhoneVerificationResendDelay = viper.GetDuration(flagForPhoneVerificationResendDelay)
+	c.SMSProvider = viper.GetString(flagForSMSProvider)
+	c.DefaultPhoneRegion = viper.GetString(flagForDefaultPhoneRegion)
+	c.SignupRequiredIdentifiers = viper.GetStringSlice(flagForSignupRequiredIdentifiers)
+	c.EmailProviderRules = viper.GetBool(flagForEmailProviderRules)
+	c.EmailDomainPolicyRefreshInterval = viper.GetDuration(flagForEmailDomainPolicyRefreshInterval)
+	c.EmailDisposableDomainsFile = viper.GetString(flagForEmailDisposableDomainsFile)
+	c.UsernameChangeCooldown = viper.GetDuration(flagForUsernameChangeCooldown)
+	c.UsernameHoldPeriod = viper.GetDuration(flagForUsernameHoldPeriod)
 
 	return c
 }
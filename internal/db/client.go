@@ -5,22 +5,36 @@ import (
 	"fmt"
 
 	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/status"
 )
 
 type TxOption string
 
 const ReadOnly = TxOption("read-only")
 
+// Background jobs that only one instance should run at a time hold
+// an advisory lock while they run. The IDs mustn't clash with migrationLockID.
+const (
+	NormalizeEmailAddressesLockID = 7235108240
+	NormalizePhoneNumbersLockID   = 7235108241
+	NormalizeUsernamesLockID      = 7235108242
+)
+
 type Client interface {
 	RunInTransaction(ctx context.Context, fn func(context.Context, Transaction) error, options ...TxOption) error
+
+	// RunExclusively runs fn while holding the advisory lock with the given ID.
+	// If another instance holds it, fn isn't run and false is returned.
+	RunExclusively(ctx context.Context, lockID int64, fn func(context.Context) error) (bool, error)
 }
 
 type clientImpl struct {
-	db *pgx.Conn
+	db *pgxpool.Pool
 }
 
-func NewClient(db *pgx.Conn) Client {
+func NewClient(db *pgxpool.Pool) Client {
 	return &clientImpl{db: db}
 }
 
@@ -43,23 +57,44 @@ func (c *clientImpl) RunInTransaction(ctx context.Context, fn func(context.Conte
 		return fmt.Errorf("failed to start sql transaction: %v", err)
 	}
 
+	// Every transaction must end, even read-only ones,
+	// or its connection is never returned to the pool.
 	defer func() {
-		if !readOnly {
-			if err := tx.Rollback(ctx); err != nil {
-				logrus.Errorf("failed to rollback transaction: %v", err)
-			}
+		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			logrus.Errorf("failed to rollback transaction: %v", err)
 		}
 	}()
 
 	// Run function
-	err = fn(ctx, newTransaction(tx))
+	err = translateError(fn(ctx, newTransaction(tx)))
 	if err != nil {
+		// Status errors are returned untouched so their codes reach the client.
+		if _, ok := status.FromError(err); ok {
+			return err
+		}
 		return fmt.Errorf("sql transaction failed: %v", err)
 	}
 
-	if !readOnly {
-		return tx.Commit(ctx)
+	return translateError(tx.Commit(ctx))
+}
+
+func (c *clientImpl) RunExclusively(ctx context.Context, lockID int64, fn func(context.Context) error) (bool, error) {
+	// Advisory locks belong to a session, so the connection that takes
+	// the lock is held until fn is done, and used to release it.
+	conn, err := c.db.Acquire(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire database connection: %w", err)
+	}
+	defer conn.Release()
+
+	var locked bool
+	if err := conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", lockID).Scan(&locked); err != nil {
+		return false, fmt.Errorf("failed to take advisory lock: %w", err)
+	}
+	if !locked {
+		return false, nil
 	}
+	defer conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", lockID)
 
-	return nil
+	return true, fn(ctx)
 }
@@ -3,15 +3,34 @@ package db
 import (
 	"context"
 	"fmt"
+	"strconv"
 
-	configuration "github.com/AlpacaLabs/go-config"
-
-	"github.com/jackc/pgx/v4"
+	"github.com/AlpacaLabs/api-account/internal/configuration"
+	"github.com/jackc/pgx/v4/pgxpool"
 )
 
-func Connect(config configuration.SQLConfig) (*pgx.Conn, error) {
+// Connect opens a pool of database connections, which unlike a single
+// connection is safe to share between concurrent requests.
+func Connect(config configuration.Config) (*pgxpool.Pool, error) {
+	sqlConfig := config.SQLConfig
+	poolConfig := config.SQLPoolConfig
+
 	connectionString := fmt.Sprintf("user=%s password=%s host=%s dbname=%s sslmode=disable",
-		config.User, config.Pass, config.Host, config.Name)
+		sqlConfig.User, sqlConfig.Pass, sqlConfig.Host, sqlConfig.Name)
+
+	c, err := pgxpool.ParseConfig(connectionString)
+	if err != nil {
+		return nil, err
+	}
+
+	c.MinConns = int32(poolConfig.MinConns)
+	c.MaxConns = int32(poolConfig.MaxConns)
+	c.MaxConnLifetime = poolConfig.MaxConnLifetime
+	c.HealthCheckPeriod = poolConfig.HealthCheckPeriod
+
+	if poolConfig.StatementTimeout > 0 {
+		c.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(poolConfig.StatementTimeout.Milliseconds(), 10)
+	}
 
-	return pgx.Connect(context.TODO(), connectionString)
+	return pgxpool.ConnectConfig(context.TODO(), c)
 }
@@ -14,4 +14,12 @@ type Account struct {
 	Username              null.String
 	CurrentPasswordID     null.String
 	PrimaryEmailAddressID null.String
+
+	// UsernameFolded is Username with case folded away, for looking up
+	// usernames however they're capitalized.
+	UsernameFolded null.String
+
+	// UsernameSkeleton is what Username looks like, for telling apart
+	// usernames that could be mistaken for each other.
+	UsernameSkeleton null.String
 }
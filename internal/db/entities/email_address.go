@@ -21,12 +21,20 @@ type EmailAddress struct {
 	Primary        bool
 	EmailAddress   string
 	AccountID      string
+
+	// CanonicalEmailAddress identifies the inbox EmailAddress delivers to.
+	// CanonicalRules names the rules it was worked out with, and is null
+	// for addresses stored before they were recorded.
+	CanonicalEmailAddress string
+	CanonicalRules        null.String
 }
 
 type NewEmailAddressInput struct {
-	Primary      bool
-	EmailAddress string
-	AccountID    string
+	Primary               bool
+	EmailAddress          string
+	CanonicalEmailAddress string
+	CanonicalRules        string
+	AccountID             string
 }
 
 func NewEmailAddress(in NewEmailAddressInput) EmailAddress {
@@ -39,6 +47,9 @@ func NewEmailAddress(in NewEmailAddressInput) EmailAddress {
 		Primary:        in.Primary,
 		EmailAddress:   in.EmailAddress,
 		AccountID:      in.AccountID,
+
+		CanonicalEmailAddress: in.CanonicalEmailAddress,
+		CanonicalRules:        null.StringFrom(in.CanonicalRules),
 	}
 }
 
@@ -55,3 +66,14 @@ func (e EmailAddress) ToProtobuf() *accountV1.EmailAddress {
 		AccountId:      e.AccountID,
 	}
 }
+
+// EmailAddressConflict records an email address that couldn't be given
+// its canonical form, because another address already has it.
+type EmailAddressConflict struct {
+	EmailAddressID            string
+	AccountID                 string
+	CanonicalEmailAddress     string
+	ConflictingEmailAddressID string
+	ConflictingAccountID      string
+	DetectedAt                time.Time
+}
@@ -0,0 +1,18 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/guregu/null"
+)
+
+// EmailConfirmationToken records a confirmation token that was mailed to
+// an email address. Only a hash of the token is stored.
+type EmailConfirmationToken struct {
+	ID             string
+	CreatedAt      time.Time
+	EmailAddressID string
+	TokenHash      []byte
+	ExpiresAt      time.Time
+	ConsumedAt     null.Time
+}
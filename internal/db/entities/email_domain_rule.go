@@ -0,0 +1,22 @@
+package entities
+
+import (
+	"time"
+)
+
+const (
+	EmailDomainActionAllow = "allow"
+	EmailDomainActionDeny  = "deny"
+)
+
+// EmailDomainRule allows or denies email addresses at a domain and its
+// subdomains, overriding the bundled disposable domain list.
+type EmailDomainRule struct {
+	Domain    string
+	Action    string
+	Reason    string
+	CreatedAt time.Time
+
+	// CreatedBy is the ID of the admin who set the rule.
+	CreatedBy string
+}
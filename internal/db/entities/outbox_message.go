@@ -0,0 +1,48 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/guregu/null"
+)
+
+// OutboxMessage is a message waiting to be relayed to Kafka.
+// It is written in the same transaction as the change it describes,
+// so the message is published if and only if the change is committed.
+type OutboxMessage struct {
+	ID            int64
+	CreatedAt     time.Time
+	Topic         string
+	Key           string
+	Headers       map[string]string
+	Payload       []byte
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     null.String
+	SentAt        null.Time
+}
+
+type NewOutboxMessageInput struct {
+	Topic   string
+	Key     string
+	Headers map[string]string
+	Payload []byte
+}
+
+func NewOutboxMessage(in NewOutboxMessageInput) OutboxMessage {
+	now := time.Now()
+
+	headers := in.Headers
+	if headers == nil {
+		headers = map[string]string{}
+	}
+
+	return OutboxMessage{
+		CreatedAt:     now,
+		Topic:         in.Topic,
+		Key:           in.Key,
+		Headers:       headers,
+		Payload:       in.Payload,
+		NextAttemptAt: now,
+	}
+}
@@ -19,10 +19,18 @@ type PhoneNumber struct {
 	Confirmed      bool
 	PhoneNumber    string
 	AccountID      string
+
+	// CountryCode and NumberType are what PhoneNumber parsed as.
+	// They're null for numbers stored before E.164 normalization
+	// that haven't been normalized yet.
+	CountryCode null.Int
+	NumberType  null.String
 }
 
 type NewPhoneNumberInput struct {
 	PhoneNumber string
+	CountryCode int
+	NumberType  string
 	AccountID   string
 }
 
@@ -35,6 +43,8 @@ func NewPhoneNumber(in NewPhoneNumberInput) PhoneNumber {
 		DeletedAt:      null.TimeFromPtr(nil),
 		PhoneNumber:    in.PhoneNumber,
 		AccountID:      in.AccountID,
+		CountryCode:    null.IntFrom(int64(in.CountryCode)),
+		NumberType:     null.StringFrom(in.NumberType),
 	}
 }
 
@@ -47,3 +57,14 @@ func (e PhoneNumber) ToProtobuf() *accountV1.PhoneNumber {
 		AccountId:   e.AccountID,
 	}
 }
+
+// PhoneNumberConflict records a phone number that couldn't be stored in
+// E.164, because another number already is.
+type PhoneNumberConflict struct {
+	PhoneNumberID            string
+	AccountID                string
+	E164PhoneNumber          string
+	ConflictingPhoneNumberID string
+	ConflictingAccountID     string
+	DetectedAt               time.Time
+}
@@ -0,0 +1,22 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/guregu/null"
+)
+
+// PhoneNumberVerification is a one-time passcode that was texted to a
+// phone number. Only a hash of the code is stored.
+type PhoneNumberVerification struct {
+	PhoneNumberID string
+	CreatedAt     time.Time
+	CodeHash      []byte
+	ExpiresAt     time.Time
+
+	// Attempts counts the wrong codes entered for the phone number,
+	// including for codes sent before this one. LastAttemptAt is when
+	// the last one was entered.
+	Attempts      int
+	LastAttemptAt null.Time
+}
@@ -0,0 +1,26 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/rs/xid"
+)
+
+// UsernameChange records a username that an account changed away from.
+type UsernameChange struct {
+	ID               string
+	AccountID        string
+	Username         string
+	UsernameSkeleton string
+	ChangedAt        time.Time
+}
+
+func NewUsernameChange(accountID, username, usernameSkeleton string, changedAt time.Time) UsernameChange {
+	return UsernameChange{
+		ID:               xid.New().String(),
+		AccountID:        accountID,
+		Username:         username,
+		UsernameSkeleton: usernameSkeleton,
+		ChangedAt:        changedAt,
+	}
+}
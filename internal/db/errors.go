@@ -0,0 +1,59 @@
+package db
+
+import (
+	"errors"
+
+	"github.com/jackc/pgconn"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	pgUniqueViolation = "23505"
+	pgCheckViolation  = "23514"
+)
+
+// ErrPrimaryEmailAddressRequired is returned when a change would leave an
+// account with email addresses but not exactly one primary among them.
+var ErrPrimaryEmailAddressRequired = status.Error(codes.FailedPrecondition,
+	"an account with email addresses must have exactly one primary email address; make another address primary first")
+
+// checkConstraintErrors maps check constraints, including those raised by
+// constraint triggers, to the error reported when they're violated.
+var checkConstraintErrors = map[string]error{
+	"account_primary_email_address_check": ErrPrimaryEmailAddressRequired,
+}
+
+// uniqueIndexFields names the field each unique index protects,
+// so a violation can be reported against it.
+var uniqueIndexFields = map[string]string{
+	"account_username_unique_idx":                      "username",
+	"account_username_skeleton_unique_idx":             "username",
+	"email_address_canonical_email_address_unique_idx": "email_address",
+	"phone_number_phone_number_unique_idx":             "phone_number",
+}
+
+// translateError turns unique violations into AlreadyExists status errors,
+// and known check violations into FailedPrecondition ones.
+// The service checks for these up front, but only the database can
+// settle a race between two concurrent writers.
+func translateError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+
+	switch pgErr.Code {
+	case pgUniqueViolation:
+		if field, ok := uniqueIndexFields[pgErr.ConstraintName]; ok {
+			return status.Errorf(codes.AlreadyExists, "%s is already in use", field)
+		}
+		return status.Error(codes.AlreadyExists, "entity already exists")
+	case pgCheckViolation:
+		if e, ok := checkConstraintErrors[pgErr.ConstraintName]; ok {
+			return e
+		}
+	}
+
+	return err
+}
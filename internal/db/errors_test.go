@@ -0,0 +1,66 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgconn"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestTranslateError(t *testing.T) {
+	plain := errors.New("connection reset")
+
+	tests := []struct {
+		name     string
+		err      error
+		wantCode codes.Code
+		wantErr  error
+	}{
+		{
+			name:     "unique violation on known index",
+			err:      &pgconn.PgError{Code: pgUniqueViolation, ConstraintName: "account_username_unique_idx"},
+			wantCode: codes.AlreadyExists,
+		},
+		{
+			name:     "unique violation on unknown index",
+			err:      &pgconn.PgError{Code: pgUniqueViolation, ConstraintName: "something_idx"},
+			wantCode: codes.AlreadyExists,
+		},
+		{
+			name:     "wrapped primary email address check",
+			err:      fmt.Errorf("commit: %w", &pgconn.PgError{Code: pgCheckViolation, ConstraintName: "account_primary_email_address_check"}),
+			wantCode: codes.FailedPrecondition,
+			wantErr:  ErrPrimaryEmailAddressRequired,
+		},
+		{
+			name:     "unknown check violation",
+			err:      &pgconn.PgError{Code: pgCheckViolation, ConstraintName: "email_address_not_empty"},
+			wantCode: codes.Unknown,
+		},
+		{
+			name:     "not a postgres error",
+			err:      plain,
+			wantCode: codes.Unknown,
+			wantErr:  plain,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := translateError(tt.err)
+			if code := status.Code(got); code != tt.wantCode {
+				t.Errorf("translateError() code = %v, want %v", code, tt.wantCode)
+			}
+			if tt.wantErr != nil && got != tt.wantErr {
+				t.Errorf("translateError() = %v, want %v", got, tt.wantErr)
+			}
+		})
+	}
+
+	if translateError(nil) != nil {
+		t.Error("translateError(nil) should be nil")
+	}
+}
@@ -0,0 +1,137 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// EmailAddressFilter narrows down a list of email addresses.
+// Zero values don't filter anything.
+type EmailAddressFilter struct {
+	Confirmed      *bool      `json:"confirmed,omitempty"`
+	PrimaryOnly    bool       `json:"primary_only,omitempty"`
+	CreatedAfter   *time.Time `json:"created_after,omitempty"`
+	CreatedBefore  *time.Time `json:"created_before,omitempty"`
+	IncludeDeleted bool       `json:"include_deleted,omitempty"`
+
+	// Domain is matched against the domain of canonical email addresses,
+	// so it has to be in canonical form too.
+	Domain string `json:"domain,omitempty"`
+
+	AccountID string `json:"account_id,omitempty"`
+}
+
+// PhoneNumberFilter narrows down a list of phone numbers.
+// Zero values don't filter anything.
+type PhoneNumberFilter struct {
+	Confirmed      *bool      `json:"confirmed,omitempty"`
+	CreatedAfter   *time.Time `json:"created_after,omitempty"`
+	CreatedBefore  *time.Time `json:"created_before,omitempty"`
+	IncludeDeleted bool       `json:"include_deleted,omitempty"`
+
+	// CountryCode is a country calling code, like 44.
+	CountryCode int `json:"country_code,omitempty"`
+
+	AccountID string `json:"account_id,omitempty"`
+}
+
+// AccountFilter narrows down a list of accounts.
+// Zero values don't filter anything.
+type AccountFilter struct {
+	CreatedAfter   *time.Time `json:"created_after,omitempty"`
+	CreatedBefore  *time.Time `json:"created_before,omitempty"`
+	IncludeDeleted bool       `json:"include_deleted,omitempty"`
+}
+
+// conditions builds a parameterized WHERE clause.
+type conditions struct {
+	clauses []string
+	args    []interface{}
+}
+
+// add appends a condition, where each %s in the format
+// is replaced by a placeholder for the next argument.
+func (c *conditions) add(format string, args ...interface{}) {
+	placeholders := make([]interface{}, len(args))
+	for i, arg := range args {
+		c.args = append(c.args, arg)
+		placeholders[i] = fmt.Sprintf("$%d", len(c.args))
+	}
+	c.clauses = append(c.clauses, fmt.Sprintf(format, placeholders...))
+}
+
+// nextArg is the number of the next placeholder.
+func (c *conditions) nextArg() int {
+	return len(c.args) + 1
+}
+
+func (c *conditions) String() string {
+	if len(c.clauses) == 0 {
+		return "TRUE"
+	}
+	return strings.Join(c.clauses, " AND ")
+}
+
+func (f EmailAddressFilter) conditions() *conditions {
+	c := &conditions{}
+	if f.Confirmed != nil {
+		c.add("confirmed = %s", *f.Confirmed)
+	}
+	if f.PrimaryOnly {
+		c.add("is_primary")
+	}
+	if f.CreatedAfter != nil {
+		c.add("created_at >= %s", *f.CreatedAfter)
+	}
+	if f.CreatedBefore != nil {
+		c.add("created_at < %s", *f.CreatedBefore)
+	}
+	if !f.IncludeDeleted {
+		c.add("deleted_at IS NULL")
+	}
+	if f.Domain != "" {
+		c.add("split_part(canonical_email_address, '@', 2) = %s", f.Domain)
+	}
+	if f.AccountID != "" {
+		c.add("account_id = %s", f.AccountID)
+	}
+	return c
+}
+
+func (f PhoneNumberFilter) conditions() *conditions {
+	c := &conditions{}
+	if f.Confirmed != nil {
+		c.add("confirmed = %s", *f.Confirmed)
+	}
+	if f.CreatedAfter != nil {
+		c.add("created_at >= %s", *f.CreatedAfter)
+	}
+	if f.CreatedBefore != nil {
+		c.add("created_at < %s", *f.CreatedBefore)
+	}
+	if !f.IncludeDeleted {
+		c.add("deleted_at IS NULL")
+	}
+	if f.CountryCode != 0 {
+		c.add("country_code = %s", f.CountryCode)
+	}
+	if f.AccountID != "" {
+		c.add("account_id = %s", f.AccountID)
+	}
+	return c
+}
+
+func (f AccountFilter) conditions() *conditions {
+	c := &conditions{}
+	if f.CreatedAfter != nil {
+		c.add("a.created_at >= %s", *f.CreatedAfter)
+	}
+	if f.CreatedBefore != nil {
+		c.add("a.created_at < %s", *f.CreatedBefore)
+	}
+	if !f.IncludeDeleted {
+		c.add("a.deleted_at IS NULL")
+	}
+	return c
+}
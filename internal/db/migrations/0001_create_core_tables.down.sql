@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS phone_number;
+DROP TABLE IF EXISTS email_address;
+DROP TABLE IF EXISTS account;
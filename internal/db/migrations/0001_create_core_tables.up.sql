@@ -0,0 +1,37 @@
+-- These tables predate versioned migrations, so this migration is a
+-- no-op for existing databases.
+
+CREATE TABLE IF NOT EXISTS account (
+  id                       TEXT PRIMARY KEY,
+  created_at               TIMESTAMPTZ NOT NULL DEFAULT now(),
+  last_modified_at         TIMESTAMPTZ NOT NULL DEFAULT now(),
+  deleted_at               TIMESTAMPTZ,
+  username                 TEXT,
+  current_password_id      TEXT,
+  primary_email_address_id TEXT
+);
+
+CREATE TABLE IF NOT EXISTS email_address (
+  id               TEXT PRIMARY KEY,
+  created_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+  last_modified_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  deleted_at       TIMESTAMPTZ,
+  confirmed        BOOLEAN NOT NULL DEFAULT FALSE,
+  is_primary       BOOLEAN NOT NULL DEFAULT FALSE,
+  email_address    TEXT NOT NULL,
+  account_id       TEXT NOT NULL REFERENCES account (id)
+);
+
+CREATE INDEX IF NOT EXISTS email_address_account_id_idx ON email_address (account_id);
+
+CREATE TABLE IF NOT EXISTS phone_number (
+  id               TEXT PRIMARY KEY,
+  created_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+  last_modified_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  deleted_at       TIMESTAMPTZ,
+  confirmed        BOOLEAN NOT NULL DEFAULT FALSE,
+  phone_number     TEXT NOT NULL,
+  account_id       TEXT NOT NULL REFERENCES account (id)
+);
+
+CREATE INDEX IF NOT EXISTS phone_number_account_id_idx ON phone_number (account_id);
@@ -0,0 +1,15 @@
+CREATE TABLE outbox (
+  id              BIGSERIAL PRIMARY KEY,
+  created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+  topic           TEXT NOT NULL,
+  message_key     TEXT NOT NULL DEFAULT '',
+  headers         JSONB NOT NULL DEFAULT '{}',
+  payload         BYTEA NOT NULL,
+  attempts        INTEGER NOT NULL DEFAULT 0,
+  next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  last_error      TEXT,
+  sent_at         TIMESTAMPTZ
+);
+
+CREATE INDEX outbox_pending_idx ON outbox (next_attempt_at) WHERE sent_at IS NULL;
+CREATE INDEX outbox_pending_key_idx ON outbox (topic, message_key, id) WHERE sent_at IS NULL;
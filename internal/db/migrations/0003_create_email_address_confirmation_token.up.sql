@@ -0,0 +1,11 @@
+CREATE TABLE email_address_confirmation_token (
+  id               TEXT PRIMARY KEY,
+  created_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+  email_address_id TEXT NOT NULL REFERENCES email_address (id) ON DELETE CASCADE,
+  token_hash       BYTEA NOT NULL,
+  expires_at       TIMESTAMPTZ NOT NULL,
+  consumed_at      TIMESTAMPTZ
+);
+
+CREATE INDEX email_address_confirmation_token_email_address_id_idx
+  ON email_address_confirmation_token (email_address_id);
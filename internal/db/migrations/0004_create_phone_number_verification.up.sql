@@ -0,0 +1,7 @@
+CREATE TABLE phone_number_verification (
+  phone_number_id TEXT PRIMARY KEY REFERENCES phone_number (id) ON DELETE CASCADE,
+  created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+  code_hash       BYTEA NOT NULL,
+  expires_at      TIMESTAMPTZ NOT NULL,
+  attempts        INTEGER NOT NULL DEFAULT 0
+);
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS email_address_primary_email_address_check ON email_address;
+DROP TRIGGER IF EXISTS account_primary_email_address_check ON account;
+DROP FUNCTION IF EXISTS check_primary_email_address();
+DROP INDEX IF EXISTS email_address_one_primary_per_account_idx;
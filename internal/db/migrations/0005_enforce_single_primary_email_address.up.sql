@@ -0,0 +1,71 @@
+-- Keep only the most recently modified primary email address per account.
+UPDATE email_address e
+  SET is_primary = FALSE
+  WHERE e.is_primary
+  AND e.deleted_at IS NULL
+  AND EXISTS (
+    SELECT 1 FROM email_address o
+    WHERE o.account_id = e.account_id
+    AND o.is_primary
+    AND o.deleted_at IS NULL
+    AND (o.last_modified_at, o.id) > (e.last_modified_at, e.id)
+  );
+
+-- Point each account at its primary email address.
+UPDATE account a
+  SET primary_email_address_id = e.id
+  FROM email_address e
+  WHERE e.account_id = a.id
+  AND e.is_primary
+  AND e.deleted_at IS NULL
+  AND a.primary_email_address_id IS DISTINCT FROM e.id;
+
+-- At most one live primary email address per account...
+CREATE UNIQUE INDEX email_address_one_primary_per_account_idx
+  ON email_address (account_id)
+  WHERE is_primary AND deleted_at IS NULL;
+
+-- ...and, checked at commit so the primary can be switched mid-transaction,
+-- at least one, which must be the one the account points at.
+CREATE FUNCTION check_primary_email_address() RETURNS trigger AS $$
+DECLARE
+  acct TEXT;
+BEGIN
+  IF TG_TABLE_NAME = 'account' THEN
+    acct := NEW.id;
+  ELSIF TG_OP = 'DELETE' THEN
+    acct := OLD.account_id;
+  ELSE
+    acct := NEW.account_id;
+  END IF;
+
+  -- Deleted accounts have no live email addresses.
+  IF NOT EXISTS (SELECT 1 FROM account WHERE id = acct AND deleted_at IS NULL) THEN
+    RETURN NULL;
+  END IF;
+
+  IF NOT EXISTS (
+    SELECT 1 FROM account a
+    JOIN email_address e ON e.id = a.primary_email_address_id
+    WHERE a.id = acct
+    AND e.account_id = a.id
+    AND e.is_primary
+    AND e.deleted_at IS NULL
+  ) THEN
+    RAISE EXCEPTION 'account % must have exactly one primary email address', acct
+      USING ERRCODE = 'check_violation', CONSTRAINT = 'account_primary_email_address_check';
+  END IF;
+
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER account_primary_email_address_check
+  AFTER INSERT OR UPDATE ON account
+  DEFERRABLE INITIALLY DEFERRED
+  FOR EACH ROW EXECUTE PROCEDURE check_primary_email_address();
+
+CREATE CONSTRAINT TRIGGER email_address_primary_email_address_check
+  AFTER INSERT OR UPDATE OR DELETE ON email_address
+  DEFERRABLE INITIALLY DEFERRED
+  FOR EACH ROW EXECUTE PROCEDURE check_primary_email_address();
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS phone_number_phone_number_unique_idx;
+DROP INDEX IF EXISTS email_address_email_address_unique_idx;
+DROP INDEX IF EXISTS account_username_unique_idx;
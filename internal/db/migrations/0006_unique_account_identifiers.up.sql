@@ -0,0 +1,47 @@
+-- Accounts created without a username used to store an empty string.
+UPDATE account SET username = NULL WHERE username = '';
+
+-- Duplicates have to be resolved by hand, since there's no telling which
+-- account should keep an identifier. List them, rather than leaving
+-- CREATE UNIQUE INDEX to fail on the first one.
+DO $$
+DECLARE
+  dupes TEXT;
+BEGIN
+  SELECT string_agg(format('%s %L (%s)', kind, value, ids), E'\n') INTO dupes FROM (
+    SELECT 'username' AS kind, username AS value, string_agg(id, ', ' ORDER BY id) AS ids
+    FROM account
+    WHERE deleted_at IS NULL AND username IS NOT NULL
+    GROUP BY username
+    HAVING count(*) > 1
+    UNION ALL
+    SELECT 'email address', email_address, string_agg(account_id, ', ' ORDER BY account_id)
+    FROM email_address
+    WHERE deleted_at IS NULL
+    GROUP BY email_address
+    HAVING count(*) > 1
+    UNION ALL
+    SELECT 'phone number', phone_number, string_agg(account_id, ', ' ORDER BY account_id)
+    FROM phone_number
+    WHERE deleted_at IS NULL AND phone_number <> ''
+    GROUP BY phone_number
+    HAVING count(*) > 1
+  ) d;
+
+  IF dupes IS NOT NULL THEN
+    RAISE EXCEPTION 'identifiers are registered by more than one account; resolve them before migrating:%', E'\n' || dupes;
+  END IF;
+END $$;
+
+CREATE UNIQUE INDEX account_username_unique_idx
+  ON account (username)
+  WHERE deleted_at IS NULL;
+
+CREATE UNIQUE INDEX email_address_email_address_unique_idx
+  ON email_address (email_address)
+  WHERE deleted_at IS NULL;
+
+-- Accounts created without a phone number have an empty placeholder row.
+CREATE UNIQUE INDEX phone_number_phone_number_unique_idx
+  ON phone_number (phone_number)
+  WHERE deleted_at IS NULL AND phone_number <> '';
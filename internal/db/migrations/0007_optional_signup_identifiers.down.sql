@@ -0,0 +1,40 @@
+CREATE OR REPLACE FUNCTION check_primary_email_address() RETURNS trigger AS $$
+DECLARE
+  acct TEXT;
+BEGIN
+  IF TG_TABLE_NAME = 'account' THEN
+    acct := NEW.id;
+  ELSIF TG_OP = 'DELETE' THEN
+    acct := OLD.account_id;
+  ELSE
+    acct := NEW.account_id;
+  END IF;
+
+  IF NOT EXISTS (SELECT 1 FROM account WHERE id = acct AND deleted_at IS NULL) THEN
+    RETURN NULL;
+  END IF;
+
+  IF NOT EXISTS (
+    SELECT 1 FROM account a
+    JOIN email_address e ON e.id = a.primary_email_address_id
+    WHERE a.id = acct
+    AND e.account_id = a.id
+    AND e.is_primary
+    AND e.deleted_at IS NULL
+  ) THEN
+    RAISE EXCEPTION 'account % must have exactly one primary email address', acct
+      USING ERRCODE = 'check_violation', CONSTRAINT = 'account_primary_email_address_check';
+  END IF;
+
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP INDEX IF EXISTS phone_number_phone_number_unique_idx;
+CREATE UNIQUE INDEX phone_number_phone_number_unique_idx
+  ON phone_number (phone_number)
+  WHERE deleted_at IS NULL AND phone_number <> '';
+
+ALTER TABLE account DROP CONSTRAINT IF EXISTS account_username_not_empty;
+ALTER TABLE email_address DROP CONSTRAINT IF EXISTS email_address_not_empty;
+ALTER TABLE phone_number DROP CONSTRAINT IF EXISTS phone_number_not_empty;
@@ -0,0 +1,57 @@
+-- Email-only signups used to get an empty placeholder phone number.
+DELETE FROM phone_number WHERE phone_number = '';
+
+ALTER TABLE phone_number
+  ADD CONSTRAINT phone_number_not_empty CHECK (phone_number <> '');
+ALTER TABLE email_address
+  ADD CONSTRAINT email_address_not_empty CHECK (email_address <> '');
+ALTER TABLE account
+  ADD CONSTRAINT account_username_not_empty CHECK (username <> '');
+
+DROP INDEX phone_number_phone_number_unique_idx;
+CREATE UNIQUE INDEX phone_number_phone_number_unique_idx
+  ON phone_number (phone_number)
+  WHERE deleted_at IS NULL;
+
+-- Accounts can now be created without an email address, in which case
+-- they have no primary one either.
+CREATE OR REPLACE FUNCTION check_primary_email_address() RETURNS trigger AS $$
+DECLARE
+  acct TEXT;
+BEGIN
+  IF TG_TABLE_NAME = 'account' THEN
+    acct := NEW.id;
+  ELSIF TG_OP = 'DELETE' THEN
+    acct := OLD.account_id;
+  ELSE
+    acct := NEW.account_id;
+  END IF;
+
+  -- Deleted accounts have no live email addresses.
+  IF NOT EXISTS (SELECT 1 FROM account WHERE id = acct AND deleted_at IS NULL) THEN
+    RETURN NULL;
+  END IF;
+
+  IF NOT EXISTS (SELECT 1 FROM email_address WHERE account_id = acct AND deleted_at IS NULL) THEN
+    IF EXISTS (SELECT 1 FROM account WHERE id = acct AND primary_email_address_id IS NOT NULL) THEN
+      RAISE EXCEPTION 'account % has no email addresses but a primary one is set', acct
+        USING ERRCODE = 'check_violation', CONSTRAINT = 'account_primary_email_address_check';
+    END IF;
+    RETURN NULL;
+  END IF;
+
+  IF NOT EXISTS (
+    SELECT 1 FROM account a
+    JOIN email_address e ON e.id = a.primary_email_address_id
+    WHERE a.id = acct
+    AND e.account_id = a.id
+    AND e.is_primary
+    AND e.deleted_at IS NULL
+  ) THEN
+    RAISE EXCEPTION 'account % must have exactly one primary email address', acct
+      USING ERRCODE = 'check_violation', CONSTRAINT = 'account_primary_email_address_check';
+  END IF;
+
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS email_address_canonical_email_address_unique_idx;
+CREATE UNIQUE INDEX email_address_email_address_unique_idx
+  ON email_address (email_address)
+  WHERE deleted_at IS NULL;
+
+ALTER TABLE email_address DROP COLUMN IF EXISTS canonical_email_address;
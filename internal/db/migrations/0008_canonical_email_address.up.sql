@@ -0,0 +1,33 @@
+-- The canonical form identifies the inbox an address delivers to, so that
+-- differently written addresses for the same inbox can't both be registered.
+-- Existing addresses only get lowercased here; provider-specific rules
+-- apply to addresses registered from now on.
+ALTER TABLE email_address ADD COLUMN canonical_email_address TEXT;
+
+UPDATE email_address SET canonical_email_address = lower(trim(email_address));
+
+DO $$
+DECLARE
+  dupes INT;
+BEGIN
+  SELECT count(*) INTO dupes FROM (
+    SELECT canonical_email_address
+    FROM email_address
+    WHERE deleted_at IS NULL
+    GROUP BY canonical_email_address
+    HAVING count(*) > 1
+  ) d;
+
+  IF dupes > 0 THEN
+    RAISE EXCEPTION '% email addresses are registered more than once in different cases; resolve them before migrating', dupes;
+  END IF;
+END $$;
+
+ALTER TABLE email_address
+  ALTER COLUMN canonical_email_address SET NOT NULL,
+  ADD CONSTRAINT email_address_canonical_not_empty CHECK (canonical_email_address <> '');
+
+DROP INDEX email_address_email_address_unique_idx;
+CREATE UNIQUE INDEX email_address_canonical_email_address_unique_idx
+  ON email_address (canonical_email_address)
+  WHERE deleted_at IS NULL;
@@ -0,0 +1,8 @@
+-- Admin-managed overrides of the bundled disposable email domain list.
+CREATE TABLE email_domain_rule (
+  domain     TEXT PRIMARY KEY CHECK (domain <> ''),
+  action     TEXT NOT NULL CHECK (action IN ('allow', 'deny')),
+  reason     TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  created_by TEXT NOT NULL DEFAULT ''
+);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS phone_number_unparsed_idx;
+
+ALTER TABLE phone_number
+  DROP COLUMN IF EXISTS number_type,
+  DROP COLUMN IF EXISTS country_code;
@@ -0,0 +1,10 @@
+-- New phone numbers are stored in E.164 along with what they parsed as.
+-- Existing rows are left NULL here, since parsing them takes libphonenumber;
+-- the service normalizes them in the background.
+ALTER TABLE phone_number
+  ADD COLUMN country_code INT,
+  ADD COLUMN number_type TEXT;
+
+CREATE INDEX phone_number_unparsed_idx
+  ON phone_number (id)
+  WHERE number_type IS NULL;
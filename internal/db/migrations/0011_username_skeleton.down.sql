@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS account_username_skeleton_unique_idx;
+
+ALTER TABLE account DROP COLUMN IF EXISTS username_skeleton;
@@ -0,0 +1,9 @@
+-- A username's skeleton is what it looks like, ignoring case, separators
+-- and lookalike characters. Existing usernames are left NULL here, since
+-- working out skeletons takes Unicode tables; the service fills them in
+-- in the background.
+ALTER TABLE account ADD COLUMN username_skeleton TEXT;
+
+CREATE UNIQUE INDEX account_username_skeleton_unique_idx
+  ON account (username_skeleton)
+  WHERE deleted_at IS NULL;
@@ -0,0 +1,19 @@
+-- Usernames that accounts have changed away from. They're kept for a while
+-- so a released username can't be claimed by someone else straight away,
+-- and so lookups by the old username can still find the account.
+CREATE TABLE username_history (
+  id                TEXT PRIMARY KEY,
+  account_id        TEXT NOT NULL REFERENCES account (id) ON DELETE CASCADE,
+  username          TEXT NOT NULL,
+  username_skeleton TEXT NOT NULL,
+  changed_at        TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX username_history_account_id_idx
+  ON username_history (account_id, changed_at);
+
+CREATE INDEX username_history_username_idx
+  ON username_history (username, changed_at);
+
+CREATE INDEX username_history_username_skeleton_idx
+  ON username_history (username_skeleton, changed_at);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS outbox_message_key_idx;
+
+DROP INDEX IF EXISTS outbox_sent_at_idx;
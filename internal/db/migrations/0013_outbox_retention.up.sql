@@ -0,0 +1,5 @@
+-- Relayed messages are deleted once they're past retention, and purging
+-- an account deletes every message keyed to it.
+CREATE INDEX outbox_sent_at_idx ON outbox (sent_at) WHERE sent_at IS NOT NULL;
+
+CREATE INDEX outbox_message_key_idx ON outbox (message_key);
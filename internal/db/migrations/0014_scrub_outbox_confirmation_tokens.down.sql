@@ -0,0 +1 @@
+-- Scrubbed tokens can't be put back, and don't need to be.
@@ -0,0 +1,6 @@
+-- Email confirmation tokens used to be stored in outbox message headers.
+-- They're minted as messages are relayed now, so scrub any left behind.
+-- Pending requests get a fresh token when they're relayed.
+UPDATE outbox
+  SET headers = headers - 'confirmation-token'
+  WHERE headers ? 'confirmation-token';
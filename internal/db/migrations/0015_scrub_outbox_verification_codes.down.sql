@@ -0,0 +1 @@
+-- Scrubbed codes can't be put back, and don't need to be.
@@ -0,0 +1,6 @@
+-- SMS verification codes used to be stored in outbox message headers.
+-- They're generated as messages are relayed now, so scrub any left behind.
+-- Pending requests get a fresh code when they're relayed.
+UPDATE outbox
+  SET headers = headers - 'verification-code'
+  WHERE headers ? 'verification-code';
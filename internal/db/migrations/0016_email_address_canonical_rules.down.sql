@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS email_address_conflict;
+
+ALTER TABLE email_address DROP COLUMN IF EXISTS canonical_rules;
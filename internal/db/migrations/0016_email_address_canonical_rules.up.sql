@@ -0,0 +1,15 @@
+-- canonical_rules names the rules an address's canonical form was worked
+-- out with. Addresses stored under other rules, including those lowercased
+-- by 0008, are worked out again in the background when the service starts.
+ALTER TABLE email_address ADD COLUMN canonical_rules TEXT;
+
+-- Addresses whose new canonical form is already taken keep their old one,
+-- and are recorded here until the conflict is resolved.
+CREATE TABLE email_address_conflict (
+  email_address_id             TEXT PRIMARY KEY REFERENCES email_address (id) ON DELETE CASCADE,
+  account_id                   TEXT NOT NULL,
+  canonical_email_address      TEXT NOT NULL,
+  conflicting_email_address_id TEXT NOT NULL REFERENCES email_address (id) ON DELETE CASCADE,
+  conflicting_account_id       TEXT NOT NULL,
+  detected_at                  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
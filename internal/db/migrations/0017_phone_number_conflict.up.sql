@@ -0,0 +1,11 @@
+-- Phone numbers stored before E.164 normalization whose E.164 form
+-- another number already has keep what was typed in, and are recorded
+-- here so the accounts involved can be sorted out.
+CREATE TABLE phone_number_conflict (
+  phone_number_id             TEXT PRIMARY KEY REFERENCES phone_number (id) ON DELETE CASCADE,
+  account_id                  TEXT NOT NULL,
+  e164_phone_number           TEXT NOT NULL,
+  conflicting_phone_number_id TEXT NOT NULL REFERENCES phone_number (id) ON DELETE CASCADE,
+  conflicting_account_id      TEXT NOT NULL,
+  detected_at                 TIMESTAMPTZ NOT NULL DEFAULT now()
+);
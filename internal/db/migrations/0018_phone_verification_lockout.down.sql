@@ -0,0 +1 @@
+ALTER TABLE phone_number_verification DROP COLUMN IF EXISTS last_attempt_at;
@@ -0,0 +1,3 @@
+-- Wrong codes are counted across resends, so the lockout is lifted a while
+-- after the last wrong code rather than by sending a new one.
+ALTER TABLE phone_number_verification ADD COLUMN last_attempt_at TIMESTAMPTZ;
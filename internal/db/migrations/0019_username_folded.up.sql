@@ -0,0 +1,10 @@
+-- A username's folded form is the username with case folded away, so
+-- lookups can ignore case without matching usernames that only look
+-- alike. It isn't unique: usernames set before skeletons were can differ
+-- only in case. Existing usernames are left NULL here, for the same
+-- reason as username_skeleton; the service fills them in in the background.
+ALTER TABLE account ADD COLUMN username_folded TEXT;
+
+CREATE INDEX account_username_folded_idx
+  ON account (username_folded)
+  WHERE deleted_at IS NULL;
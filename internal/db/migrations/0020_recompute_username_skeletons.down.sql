@@ -0,0 +1 @@
+-- Recomputed skeletons don't need to be put back.
@@ -0,0 +1,5 @@
+-- Skeletons used to map i to l, which made distinct usernames like "eli"
+-- and "ell" collide. Clearing them has the service work them out again
+-- in the background. Skeletons only get more distinct, so none of the
+-- recomputed ones can collide where the old ones didn't.
+UPDATE account SET username_skeleton = NULL WHERE username_skeleton IS NOT NULL;
@@ -0,0 +1,10 @@
+// Package migrations embeds the versioned SQL schema migrations.
+//
+// Each version has an up and a down file, named <version>_<name>.up.sql
+// and <version>_<name>.down.sql. Versions are applied in ascending order.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
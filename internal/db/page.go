@@ -0,0 +1,237 @@
+package db
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	paginationV1 "github.com/AlpacaLabs/protorepo-pagination-go/alpacalabs/pagination/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// PageRequest asks for one page of a list.
+type PageRequest struct {
+	// SortClauses order the list. Only whitelisted fields are allowed,
+	// and the ID is always used to break ties.
+	SortClauses []*paginationV1.SortClause
+
+	// After is the sort key to continue from, or empty for the first page.
+	// Rows strictly after it are returned, or strictly before it when
+	// paging backward.
+	After PageKey
+
+	// Backward pages toward the start of the list.
+	Backward bool
+
+	Limit int
+}
+
+// PageKey is a row's sort key: the text value of each column it's sorted by.
+type PageKey []string
+
+// PageInfo describes the page that was returned, whose rows are always
+// in the requested sort order, whichever direction was paged in.
+type PageInfo struct {
+	FirstKey PageKey
+	LastKey  PageKey
+
+	// HasMore reports whether there are more rows beyond this page
+	// in the direction being paged.
+	HasMore bool
+}
+
+// sortColumn is a column that lists can be sorted by.
+type sortColumn struct {
+	// expr is the SQL expression sorted on. It must never be NULL,
+	// or rows would be skipped when paging past them.
+	expr string
+
+	// typ is the SQL type that key values are cast back to.
+	typ string
+}
+
+// sortableColumns whitelists the fields an entity's lists can be sorted by,
+// so client input never ends up in SQL.
+type sortableColumns map[string]sortColumn
+
+var (
+	accountSortColumns = sortableColumns{
+		"id":               {expr: "a.id", typ: "text"},
+		"created_at":       {expr: "a.created_at", typ: "timestamptz"},
+		"last_modified_at": {expr: "a.last_modified_at", typ: "timestamptz"},
+		"username":         {expr: "COALESCE(a.username, '')", typ: "text"},
+	}
+
+	emailAddressSortColumns = sortableColumns{
+		"id":               {expr: "id", typ: "text"},
+		"created_at":       {expr: "created_at", typ: "timestamptz"},
+		"last_modified_at": {expr: "last_modified_at", typ: "timestamptz"},
+		"email_address":    {expr: "email_address", typ: "text"},
+		"account_id":       {expr: "account_id", typ: "text"},
+	}
+
+	phoneNumberSortColumns = sortableColumns{
+		"id":               {expr: "id", typ: "text"},
+		"created_at":       {expr: "created_at", typ: "timestamptz"},
+		"last_modified_at": {expr: "last_modified_at", typ: "timestamptz"},
+		"phone_number":     {expr: "phone_number", typ: "text"},
+		"account_id":       {expr: "account_id", typ: "text"},
+	}
+)
+
+// keyset is a validated sort order.
+type keyset struct {
+	columns    []sortColumn
+	descending []bool
+}
+
+func (c sortableColumns) keyset(clauses []*paginationV1.SortClause) (keyset, error) {
+	var k keyset
+	seen := make(map[string]bool)
+
+	for _, clause := range clauses {
+		if clause == nil {
+			continue
+		}
+		column, ok := c[clause.FieldName]
+		if !ok {
+			return keyset{}, status.Errorf(codes.InvalidArgument, "cannot sort by %q", clause.FieldName)
+		}
+		if seen[clause.FieldName] {
+			return keyset{}, status.Errorf(codes.InvalidArgument, "cannot sort by %q more than once", clause.FieldName)
+		}
+		seen[clause.FieldName] = true
+
+		k.columns = append(k.columns, column)
+		k.descending = append(k.descending, clause.Sort == paginationV1.Sort_SORT_DESC)
+	}
+
+	// IDs are unique, so sorting by them last makes the order total.
+	if !seen["id"] {
+		k.columns = append(k.columns, c["id"])
+		k.descending = append(k.descending, false)
+	}
+
+	return k, nil
+}
+
+// orderBy returns the ORDER BY clause, without the keywords.
+func (k keyset) orderBy() string {
+	var arr []string
+	for i, column := range k.columns {
+		if k.descending[i] {
+			arr = append(arr, column.expr+" DESC")
+		} else {
+			arr = append(arr, column.expr+" ASC")
+		}
+	}
+	return strings.Join(arr, ", ")
+}
+
+// selectKey returns a SELECT expression yielding each row's PageKey.
+func (k keyset) selectKey() string {
+	var arr []string
+	for _, column := range k.columns {
+		arr = append(arr, column.expr+"::text")
+	}
+	return "ARRAY[" + strings.Join(arr, ", ") + "]"
+}
+
+// after returns a condition matching the rows that come after the given key,
+// using placeholders numbered from firstArg. For a sort on (a ASC, b DESC)
+// that's: a > $1 OR (a = $1 AND b < $2).
+func (k keyset) after(key PageKey, firstArg int) (string, []interface{}, error) {
+	if len(key) != len(k.columns) {
+		return "", nil, status.Error(codes.InvalidArgument, "cursor does not match the sort order")
+	}
+
+	var args []interface{}
+	var placeholders []string
+	for i, column := range k.columns {
+		args = append(args, key[i])
+		placeholders = append(placeholders, fmt.Sprintf("$%d::%s", firstArg+i, column.typ))
+	}
+
+	var disjuncts []string
+	for i, column := range k.columns {
+		var conjuncts []string
+		for j := 0; j < i; j++ {
+			conjuncts = append(conjuncts, fmt.Sprintf("%s = %s", k.columns[j].expr, placeholders[j]))
+		}
+
+		op := ">"
+		if k.descending[i] {
+			op = "<"
+		}
+		conjuncts = append(conjuncts, fmt.Sprintf("%s %s %s", column.expr, op, placeholders[i]))
+
+		disjuncts = append(disjuncts, "("+strings.Join(conjuncts, " AND ")+")")
+	}
+
+	return "(" + strings.Join(disjuncts, " OR ") + ")", args, nil
+}
+
+// pageClauses returns the keyset condition (or TRUE on the first page),
+// its arguments, the ORDER BY clause and the sort key expression.
+// Paging backward walks the list in reverse, which pageInfo undoes.
+func (c sortableColumns) pageClauses(request PageRequest, firstArg int) (where string, args []interface{}, orderBy, selectKey string, err error) {
+	k, err := c.keyset(request.SortClauses)
+	if err != nil {
+		return "", nil, "", "", err
+	}
+
+	if request.Backward {
+		for i := range k.descending {
+			k.descending[i] = !k.descending[i]
+		}
+	}
+
+	where = "TRUE"
+	if len(request.After) > 0 {
+		where, args, err = k.after(request.After, firstArg)
+		if err != nil {
+			return "", nil, "", "", err
+		}
+	}
+
+	return where, args, k.orderBy(), k.selectKey(), nil
+}
+
+// fetchLimit is how many rows to query for: one more than the page size,
+// to find out whether there are more.
+func (p PageRequest) fetchLimit() int {
+	return p.Limit + 1
+}
+
+// pageInfo trims the rows fetched for a page, given the sort key of each in
+// the order they were queried. It returns how many rows to keep. When paging
+// backward, the kept rows must then be reversed with reverseRows.
+func (p PageRequest) pageInfo(keys []PageKey) (PageInfo, int) {
+	info := PageInfo{}
+
+	n := len(keys)
+	if n > p.Limit {
+		info.HasMore = true
+		n = p.Limit
+	}
+	if n == 0 {
+		return info, 0
+	}
+
+	info.FirstKey, info.LastKey = keys[0], keys[n-1]
+	if p.Backward {
+		info.FirstKey, info.LastKey = info.LastKey, info.FirstKey
+	}
+
+	return info, n
+}
+
+// reverseRows reverses a slice in place.
+func reverseRows(rows interface{}) {
+	swap := reflect.Swapper(rows)
+	n := reflect.ValueOf(rows).Len()
+	for i := 0; i < n/2; i++ {
+		swap(i, n-1-i)
+	}
+}
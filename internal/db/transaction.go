@@ -1,14 +1,9 @@
 package db
 
 import (
-	"fmt"
-	"strings"
-
 	"github.com/jackc/pgx/v4"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
-
-	paginationV1 "github.com/AlpacaLabs/protorepo-pagination-go/alpacalabs/pagination/v1"
 )
 
 var (
@@ -19,12 +14,22 @@ type Transaction interface {
 	AccountTransaction
 	EmailTransaction
 	PhoneTransaction
+	OutboxTransaction
+	EmailConfirmationTransaction
+	PhoneVerificationTransaction
+	EmailDomainTransaction
+	UsernameHistoryTransaction
 }
 
 type txImpl struct {
 	accountTxImpl
 	emailTxImpl
 	phoneTxImpl
+	outboxTxImpl
+	emailConfirmationTxImpl
+	phoneVerificationTxImpl
+	emailDomainTxImpl
+	usernameHistoryTxImpl
 }
 
 func newTransaction(tx pgx.Tx) Transaction {
@@ -38,21 +43,20 @@ func newTransaction(tx pgx.Tx) Transaction {
 		phoneTxImpl: phoneTxImpl{
 			tx: tx,
 		},
+		outboxTxImpl: outboxTxImpl{
+			tx: tx,
+		},
+		emailConfirmationTxImpl: emailConfirmationTxImpl{
+			tx: tx,
+		},
+		phoneVerificationTxImpl: phoneVerificationTxImpl{
+			tx: tx,
+		},
+		emailDomainTxImpl: emailDomainTxImpl{
+			tx: tx,
+		},
+		usernameHistoryTxImpl: usernameHistoryTxImpl{
+			tx: tx,
+		},
 	}
 }
-
-func buildOrderByClause(request paginationV1.CursorRequest) string {
-	var arr []string
-	for _, sortClause := range request.SortClauses {
-		sortString := sortKeyword(sortClause.Sort)
-		arr = append(arr, fmt.Sprintf("%s %s", sortClause.FieldName, sortString))
-	}
-	return strings.Join(arr, ", ")
-}
-
-func sortKeyword(sort paginationV1.Sort) string {
-	if sort == paginationV1.Sort_SORT_DESC {
-		return "DESC"
-	}
-	return "ASC"
-}
@@ -5,8 +5,6 @@ import (
 	"fmt"
 	"time"
 
-	paginationV1 "github.com/AlpacaLabs/protorepo-pagination-go/alpacalabs/pagination/v1"
-
 	"github.com/AlpacaLabs/api-account/internal/db/entities"
 	"github.com/jackc/pgx/v4"
 )
@@ -14,12 +12,26 @@ import (
 type AccountTransaction interface {
 	GetAccountByID(ctx context.Context, accountID string) (*entities.Account, error)
 	GetAccountByUsername(ctx context.Context, username string) (*entities.Account, error)
+	GetAccountByFoldedUsername(ctx context.Context, folded string) (*entities.Account, error)
+	GetAccountByUsernameSkeleton(ctx context.Context, skeleton string) (*entities.Account, error)
 	GetAccountByEmailAddress(ctx context.Context, emailAddress string) (*entities.Account, error)
 	GetAccountByPhoneNumber(ctx context.Context, phoneNumber string) (*entities.Account, error)
-	UpdateAccount(ctx context.Context, username, primaryEmailAddressID, accountID string) error
+	UpdateAccount(ctx context.Context, a entities.Account, lastModifiedAt time.Time) (int, error)
 	UpdateCurrentPassword(ctx context.Context, currentPasswordID, accountID string) error
-	CreateAccount(ctx context.Context, accountID, username string) error
-	GetAccounts(ctx context.Context, cursorRequest paginationV1.CursorRequest) ([]*entities.Account, error)
+	CreateAccount(ctx context.Context, accountID, username, usernameFolded, usernameSkeleton string) error
+	GetAccounts(ctx context.Context, filter AccountFilter, page PageRequest) ([]*entities.Account, PageInfo, error)
+	GetAccountsByIDs(ctx context.Context, accountIDs []string) ([]*entities.Account, error)
+	GetAccountsByUsernames(ctx context.Context, usernames []string) ([]*entities.Account, error)
+	GetAccountsByFoldedUsernames(ctx context.Context, folded []string) ([]*entities.Account, error)
+
+	GetDeletedAccountByID(ctx context.Context, accountID string) (*entities.Account, error)
+	DeleteAccount(ctx context.Context, accountID string, deletedAt time.Time) (int, error)
+	RestoreAccount(ctx context.Context, accountID string, deletedAt time.Time) (int, error)
+	PurgeAccounts(ctx context.Context, deletedBefore time.Time, limit int) (int, error)
+
+	GetAccountsWithUnnormalizedUsernames(ctx context.Context, afterID string, limit int) ([]*entities.Account, error)
+	UpdateUsernameFolded(ctx context.Context, accountID, folded string) error
+	UpdateUsernameSkeleton(ctx context.Context, accountID, skeleton string) error
 }
 
 type accountTxImpl struct {
@@ -32,14 +44,14 @@ func (tx *accountTxImpl) GetAccountByID(ctx context.Context, accountID string) (
 	query := `
 SELECT 
     id, created_at, last_modified_at, deleted_at, 
-    username, current_password_id, primary_email_address_id 
+    username, current_password_id, primary_email_address_id, username_skeleton, username_folded 
  FROM account
  WHERE id=$1 
  AND deleted_at IS NULL
 `
 
 	row := tx.tx.QueryRow(ctx, query, accountID)
-	err := row.Scan(&e.ID, &e.CreatedAt, &e.LastModifiedAt, &e.DeletedAt, &e.Username, &e.CurrentPasswordID, &e.PrimaryEmailAddressID)
+	err := row.Scan(&e.ID, &e.CreatedAt, &e.LastModifiedAt, &e.DeletedAt, &e.Username, &e.CurrentPasswordID, &e.PrimaryEmailAddressID, &e.UsernameSkeleton, &e.UsernameFolded)
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
@@ -57,13 +69,64 @@ func (tx *accountTxImpl) GetAccountByUsername(ctx context.Context, username stri
 	query := `
 SELECT 
     id, created_at, last_modified_at, deleted_at, 
-    username, current_password_id, primary_email_address_id
+    username, current_password_id, primary_email_address_id, username_skeleton, username_folded
   FROM account 
   WHERE username=$1
   AND deleted_at IS NULL
 `
 	row := tx.tx.QueryRow(ctx, query, username)
-	err := row.Scan(&e.ID, &e.CreatedAt, &e.LastModifiedAt, &e.DeletedAt, &e.Username, &e.CurrentPasswordID, &e.PrimaryEmailAddressID)
+	err := row.Scan(&e.ID, &e.CreatedAt, &e.LastModifiedAt, &e.DeletedAt, &e.Username, &e.CurrentPasswordID, &e.PrimaryEmailAddressID, &e.UsernameSkeleton, &e.UsernameFolded)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &e, nil
+}
+
+// GetAccountByFoldedUsername looks up the account whose username is the
+// same as the given one, ignoring case. Usernames set before skeletons were
+// unique can share a folded form, so a folded username that matches more
+// than one account matches none.
+func (tx *accountTxImpl) GetAccountByFoldedUsername(ctx context.Context, folded string) (*entities.Account, error) {
+	query := `
+SELECT 
+    id, created_at, last_modified_at, deleted_at, 
+    username, current_password_id, primary_email_address_id, username_skeleton, username_folded
+  FROM account 
+  WHERE username_folded=$1
+  AND deleted_at IS NULL
+  FETCH FIRST 2 ROWS ONLY
+`
+	accounts, err := tx.queryAccounts(ctx, query, folded)
+	if err != nil {
+		return nil, err
+	}
+	if len(accounts) != 1 {
+		return nil, ErrNotFound
+	}
+
+	return accounts[0], nil
+}
+
+// GetAccountByUsernameSkeleton looks up the account whose username looks like
+// the given skeleton.
+func (tx *accountTxImpl) GetAccountByUsernameSkeleton(ctx context.Context, skeleton string) (*entities.Account, error) {
+	var e entities.Account
+
+	query := `
+SELECT 
+    id, created_at, last_modified_at, deleted_at, 
+    username, current_password_id, primary_email_address_id, username_skeleton, username_folded
+  FROM account 
+  WHERE username_skeleton=$1
+  AND deleted_at IS NULL
+`
+	row := tx.tx.QueryRow(ctx, query, skeleton)
+	err := row.Scan(&e.ID, &e.CreatedAt, &e.LastModifiedAt, &e.DeletedAt, &e.Username, &e.CurrentPasswordID, &e.PrimaryEmailAddressID, &e.UsernameSkeleton, &e.UsernameFolded)
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
@@ -75,20 +138,22 @@ SELECT
 	return &e, nil
 }
 
+// GetAccountByEmailAddress looks up an account by the canonical form of one of its email addresses.
 func (tx *accountTxImpl) GetAccountByEmailAddress(ctx context.Context, emailAddress string) (*entities.Account, error) {
 	var e entities.Account
 
 	query := `
 SELECT
     a.id, a.created_at, a.last_modified_at, a.deleted_at,
-    a.username, a.current_password_id, a.primary_email_address_id
+    a.username, a.current_password_id, a.primary_email_address_id, a.username_skeleton, a.username_folded
   FROM email_address e 
   JOIN account a ON e.account_id = a.id
-  WHERE e.email_address=$1 
+  WHERE e.canonical_email_address=$1 
+  AND e.deleted_at IS NULL
   AND a.deleted_at IS NULL
 `
 	row := tx.tx.QueryRow(ctx, query, emailAddress)
-	err := row.Scan(&e.ID, &e.CreatedAt, &e.LastModifiedAt, &e.DeletedAt, &e.Username, &e.CurrentPasswordID, &e.PrimaryEmailAddressID)
+	err := row.Scan(&e.ID, &e.CreatedAt, &e.LastModifiedAt, &e.DeletedAt, &e.Username, &e.CurrentPasswordID, &e.PrimaryEmailAddressID, &e.UsernameSkeleton, &e.UsernameFolded)
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
@@ -106,14 +171,15 @@ func (tx *accountTxImpl) GetAccountByPhoneNumber(ctx context.Context, phoneNumbe
 	query := `
 SELECT
     a.id, a.created_at, a.last_modified_at, a.deleted_at,
-    a.username, a.current_password_id, a.primary_email_address_id
+    a.username, a.current_password_id, a.primary_email_address_id, a.username_skeleton, a.username_folded
   FROM phone_number p 
   JOIN account a ON p.account_id = a.id
   WHERE p.phone_number=$1 
+  AND p.deleted_at IS NULL
   AND a.deleted_at IS NULL
 `
 	row := tx.tx.QueryRow(ctx, query, phoneNumber)
-	err := row.Scan(&e.ID, &e.CreatedAt, &e.LastModifiedAt, &e.DeletedAt, &e.Username, &e.CurrentPasswordID, &e.PrimaryEmailAddressID)
+	err := row.Scan(&e.ID, &e.CreatedAt, &e.LastModifiedAt, &e.DeletedAt, &e.Username, &e.CurrentPasswordID, &e.PrimaryEmailAddressID, &e.UsernameSkeleton, &e.UsernameFolded)
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
@@ -125,16 +191,26 @@ SELECT
 	return &e, nil
 }
 
-func (tx *accountTxImpl) UpdateAccount(ctx context.Context, username, primaryEmailAddressID, accountID string) error {
+// UpdateAccount overwrites the account's username, its folded form and skeleton, and primary email address ID,
+// but only if the row's last_modified_at still equals lastModifiedAt.
+// It returns the number of rows affected, which is 0 if the account was
+// modified (or deleted) in the meantime.
+func (tx *accountTxImpl) UpdateAccount(ctx context.Context, a entities.Account, lastModifiedAt time.Time) (int, error) {
 	query := `
 UPDATE account 
-  SET last_modified_at=$1, username=$2, primary_email_address_id=$3
-  WHERE id=$4
+  SET last_modified_at=$1, username=$2, username_folded=$3, username_skeleton=$4, primary_email_address_id=$5
+  WHERE id=$6
+  AND last_modified_at=$7
+  AND deleted_at IS NULL
 `
 
-	_, err := tx.tx.Exec(ctx, query,
-		time.Now(), username, primaryEmailAddressID, accountID)
-	return err
+	res, err := tx.tx.Exec(ctx, query,
+		a.LastModifiedAt, a.Username, a.UsernameFolded, a.UsernameSkeleton, a.PrimaryEmailAddressID, a.ID, lastModifiedAt)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(res.RowsAffected()), nil
 }
 
 func (tx *accountTxImpl) UpdateCurrentPassword(ctx context.Context, currentPasswordID, accountID string) error {
@@ -147,37 +223,116 @@ UPDATE account
 	return err
 }
 
-func (tx *accountTxImpl) CreateAccount(ctx context.Context, accountID, username string) error {
+func (tx *accountTxImpl) CreateAccount(ctx context.Context, accountID, username, usernameFolded, usernameSkeleton string) error {
 	query := `
-INSERT INTO account(id, created_at, username)
-  VALUES($1, $2, $3)
+INSERT INTO account(id, created_at, username, username_folded, username_skeleton)
+  VALUES($1, $2, NULLIF($3, ''), NULLIF($4, ''), NULLIF($5, ''))
 `
 	_, err := tx.tx.Exec(ctx, query,
-		accountID, time.Now(), username)
+		accountID, time.Now(), username, usernameFolded, usernameSkeleton)
 	return err
 }
 
-func (tx *accountTxImpl) GetAccounts(ctx context.Context, cursorRequest paginationV1.CursorRequest) ([]*entities.Account, error) {
-	var sortString string
-	if len(cursorRequest.SortClauses) == 0 {
-		sortString = "id ASC"
-	} else {
-		sortString = buildOrderByClause(cursorRequest)
+func (tx *accountTxImpl) GetAccounts(ctx context.Context, filter AccountFilter, page PageRequest) ([]*entities.Account, PageInfo, error) {
+	where := filter.conditions()
+
+	after, args, orderBy, selectKey, err := accountSortColumns.pageClauses(page, where.nextArg())
+	if err != nil {
+		return nil, PageInfo{}, err
 	}
 
 	queryTemplate := `
 SELECT 
     a.id, a.created_at, a.last_modified_at, a.deleted_at, 
-    a.username, a.current_password_id, a.primary_email_address_id 
+    a.username, a.current_password_id, a.primary_email_address_id, a.username_skeleton, a.username_folded, %s 
   FROM account a
-  WHERE a.id > $1
-  ORDER BY a.id %s
+  WHERE %s
+  AND %s
+  ORDER BY %s
   FETCH FIRST %d ROWS ONLY
 `
 
-	query := fmt.Sprintf(queryTemplate, sortString, cursorRequest.Count)
-	rows, err := tx.tx.Query(ctx, query, cursorRequest.Cursor)
+	query := fmt.Sprintf(queryTemplate, selectKey, where, after, orderBy, page.fetchLimit())
+	rows, err := tx.tx.Query(ctx, query, append(where.args, args...)...)
 
+	if err != nil {
+		return nil, PageInfo{}, err
+	}
+
+	defer rows.Close()
+
+	accounts := []*entities.Account{}
+	var keys []PageKey
+
+	for rows.Next() {
+		var a entities.Account
+		var key PageKey
+		if err := rows.Scan(&a.ID, &a.CreatedAt, &a.LastModifiedAt, &a.DeletedAt,
+			&a.Username, &a.CurrentPasswordID, &a.PrimaryEmailAddressID, &a.UsernameSkeleton, &a.UsernameFolded, &key); err != nil {
+			return nil, PageInfo{}, err
+		}
+		accounts = append(accounts, &a)
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, PageInfo{}, err
+	}
+
+	info, n := page.pageInfo(keys)
+	accounts = accounts[:n]
+	if page.Backward {
+		reverseRows(accounts)
+	}
+
+	return accounts, info, nil
+}
+
+// GetAccountsByIDs retrieves the accounts with the given IDs, in no particular order.
+// IDs that don't match an account are left out.
+func (tx *accountTxImpl) GetAccountsByIDs(ctx context.Context, accountIDs []string) ([]*entities.Account, error) {
+	query := `
+SELECT 
+    id, created_at, last_modified_at, deleted_at, 
+    username, current_password_id, primary_email_address_id, username_skeleton, username_folded 
+  FROM account
+  WHERE id = ANY($1)
+  AND deleted_at IS NULL
+`
+	return tx.queryAccounts(ctx, query, accountIDs)
+}
+
+// GetAccountsByUsernames retrieves the accounts with the given usernames, in no particular order.
+// Usernames that don't match an account are left out.
+func (tx *accountTxImpl) GetAccountsByUsernames(ctx context.Context, usernames []string) ([]*entities.Account, error) {
+	query := `
+SELECT 
+    id, created_at, last_modified_at, deleted_at, 
+    username, current_password_id, primary_email_address_id, username_skeleton, username_folded 
+  FROM account
+  WHERE username = ANY($1)
+  AND deleted_at IS NULL
+`
+	return tx.queryAccounts(ctx, query, usernames)
+}
+
+// GetAccountsByFoldedUsernames retrieves the accounts whose usernames are the
+// same as the given ones, ignoring case, in no particular order. Folded
+// usernames that don't match an account are left out; ones that match more
+// than one account match all of them.
+func (tx *accountTxImpl) GetAccountsByFoldedUsernames(ctx context.Context, folded []string) ([]*entities.Account, error) {
+	query := `
+SELECT 
+    id, created_at, last_modified_at, deleted_at, 
+    username, current_password_id, primary_email_address_id, username_skeleton, username_folded 
+  FROM account
+  WHERE username_folded = ANY($1)
+  AND deleted_at IS NULL
+`
+	return tx.queryAccounts(ctx, query, folded)
+}
+
+func (tx *accountTxImpl) queryAccounts(ctx context.Context, query string, args ...interface{}) ([]*entities.Account, error) {
+	rows, err := tx.tx.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -189,11 +344,180 @@ SELECT
 	for rows.Next() {
 		var a entities.Account
 		if err := rows.Scan(&a.ID, &a.CreatedAt, &a.LastModifiedAt, &a.DeletedAt,
-			&a.Username, &a.CurrentPasswordID, &a.PrimaryEmailAddressID); err != nil {
+			&a.Username, &a.CurrentPasswordID, &a.PrimaryEmailAddressID, &a.UsernameSkeleton, &a.UsernameFolded); err != nil {
 			return nil, err
 		}
 		accounts = append(accounts, &a)
 	}
 
-	return accounts, nil
+	return accounts, rows.Err()
+}
+
+// GetDeletedAccountByID retrieves an account that has been soft-deleted.
+func (tx *accountTxImpl) GetDeletedAccountByID(ctx context.Context, accountID string) (*entities.Account, error) {
+	var e entities.Account
+
+	query := `
+SELECT 
+    id, created_at, last_modified_at, deleted_at, 
+    username, current_password_id, primary_email_address_id, username_skeleton, username_folded 
+ FROM account
+ WHERE id=$1 
+ AND deleted_at IS NOT NULL
+`
+
+	row := tx.tx.QueryRow(ctx, query, accountID)
+	err := row.Scan(&e.ID, &e.CreatedAt, &e.LastModifiedAt, &e.DeletedAt, &e.Username, &e.CurrentPasswordID, &e.PrimaryEmailAddressID, &e.UsernameSkeleton, &e.UsernameFolded)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &e, nil
+}
+
+// DeleteAccount soft-deletes an account, along with its email addresses
+// and phone numbers. Every row gets the same deleted_at, which is how
+// RestoreAccount later knows which rows went away with the account.
+func (tx *accountTxImpl) DeleteAccount(ctx context.Context, accountID string, deletedAt time.Time) (int, error) {
+	res, err := tx.tx.Exec(ctx, `
+UPDATE account 
+ SET deleted_at=$1, last_modified_at=$1 
+ WHERE id=$2 
+ AND deleted_at IS NULL
+`, deletedAt, accountID)
+	if err != nil {
+		return 0, err
+	}
+
+	if res.RowsAffected() == 0 {
+		return 0, nil
+	}
+
+	if _, err := tx.tx.Exec(ctx, `
+UPDATE email_address 
+ SET deleted_at=$1, last_modified_at=$1 
+ WHERE account_id=$2 
+ AND deleted_at IS NULL
+`, deletedAt, accountID); err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.tx.Exec(ctx, `
+UPDATE phone_number 
+ SET deleted_at=$1, last_modified_at=$1 
+ WHERE account_id=$2 
+ AND deleted_at IS NULL
+`, deletedAt, accountID); err != nil {
+		return 0, err
+	}
+
+	return int(res.RowsAffected()), nil
+}
+
+// RestoreAccount reverses DeleteAccount. Only rows that were deleted along
+// with the account (i.e., at exactly deletedAt) are restored.
+func (tx *accountTxImpl) RestoreAccount(ctx context.Context, accountID string, deletedAt time.Time) (int, error) {
+	now := time.Now()
+
+	res, err := tx.tx.Exec(ctx, `
+UPDATE account 
+ SET deleted_at=NULL, last_modified_at=$1 
+ WHERE id=$2 
+ AND deleted_at=$3
+`, now, accountID, deletedAt)
+	if err != nil {
+		return 0, err
+	}
+
+	if res.RowsAffected() == 0 {
+		return 0, nil
+	}
+
+	if _, err := tx.tx.Exec(ctx, `
+UPDATE email_address 
+ SET deleted_at=NULL, last_modified_at=$1 
+ WHERE account_id=$2 
+ AND deleted_at=$3
+`, now, accountID, deletedAt); err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.tx.Exec(ctx, `
+UPDATE phone_number 
+ SET deleted_at=NULL, last_modified_at=$1 
+ WHERE account_id=$2 
+ AND deleted_at=$3
+`, now, accountID, deletedAt); err != nil {
+		return 0, err
+	}
+
+	return int(res.RowsAffected()), nil
+}
+
+// PurgeAccounts permanently deletes up to limit accounts that were
+// soft-deleted before the given time, along with all of their email
+// addresses and phone numbers, and every outbox message keyed to them,
+// since those carry contact details too. It returns the number of
+// accounts purged.
+func (tx *accountTxImpl) PurgeAccounts(ctx context.Context, deletedBefore time.Time, limit int) (int, error) {
+	query := `
+WITH purged AS (
+  SELECT id 
+   FROM account 
+   WHERE deleted_at < $1 
+   ORDER BY deleted_at 
+   LIMIT $2 
+   FOR UPDATE SKIP LOCKED
+), purged_email_addresses AS (
+  DELETE FROM email_address WHERE account_id IN (SELECT id FROM purged)
+), purged_phone_numbers AS (
+  DELETE FROM phone_number WHERE account_id IN (SELECT id FROM purged)
+), purged_outbox_messages AS (
+  DELETE FROM outbox WHERE message_key IN (SELECT id FROM purged)
+)
+DELETE FROM account WHERE id IN (SELECT id FROM purged)
+`
+
+	res, err := tx.tx.Exec(ctx, query, deletedBefore, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(res.RowsAffected()), nil
+}
+
+// GetAccountsWithUnnormalizedUsernames returns accounts with usernames set
+// before their skeletons or folded forms were stored, in ID order, starting
+// after afterID.
+func (tx *accountTxImpl) GetAccountsWithUnnormalizedUsernames(ctx context.Context, afterID string, limit int) ([]*entities.Account, error) {
+	query := `
+SELECT 
+    id, created_at, last_modified_at, deleted_at, 
+    username, current_password_id, primary_email_address_id, username_skeleton, username_folded 
+  FROM account
+  WHERE username IS NOT NULL
+  AND (username_skeleton IS NULL OR username_folded IS NULL)
+  AND id > $1
+  ORDER BY id
+  FETCH FIRST $2 ROWS ONLY
+`
+	return tx.queryAccounts(ctx, query, afterID, limit)
+}
+
+// UpdateUsernameFolded stores the folded form of an account's username,
+// without counting as a modification of the account.
+func (tx *accountTxImpl) UpdateUsernameFolded(ctx context.Context, accountID, folded string) error {
+	_, err := tx.tx.Exec(ctx, "UPDATE account SET username_folded=$1 WHERE id=$2", folded, accountID)
+	return err
+}
+
+// UpdateUsernameSkeleton stores the skeleton of an account's username,
+// without counting as a modification of the account.
+func (tx *accountTxImpl) UpdateUsernameSkeleton(ctx context.Context, accountID, skeleton string) error {
+	_, err := tx.tx.Exec(ctx, "UPDATE account SET username_skeleton=$1 WHERE id=$2", skeleton, accountID)
+	return err
 }
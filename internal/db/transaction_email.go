@@ -3,11 +3,10 @@ package db
 import (
 	"context"
 	"fmt"
-	"strings"
+	"time"
 
 	"github.com/AlpacaLabs/api-account/internal/db/entities"
 	accountV1 "github.com/AlpacaLabs/protorepo-account-go/alpacalabs/account/v1"
-	paginationV1 "github.com/AlpacaLabs/protorepo-pagination-go/alpacalabs/pagination/v1"
 	"github.com/jackc/pgx/v4"
 )
 
@@ -15,18 +14,25 @@ type EmailTransaction interface {
 	CreateEmailAddress(ctx context.Context, e entities.EmailAddress) error
 	DeleteEmailAddress(ctx context.Context, id string) (int, error)
 	ConfirmEmailAddress(ctx context.Context, id string) error
+	UpdatePrimaryEmailAddress(ctx context.Context, accountID, emailAddressID string) error
 
 	GetEmailAddressByEmailAddress(ctx context.Context, emailAddress string) (*accountV1.EmailAddress, error)
 	GetEmailAddressByID(ctx context.Context, id string) (*accountV1.EmailAddress, error)
 
-	GetEmailAddresses(ctx context.Context, request paginationV1.CursorRequest) ([]*accountV1.EmailAddress, error)
+	GetEmailAddresses(ctx context.Context, filter EmailAddressFilter, page PageRequest) ([]*accountV1.EmailAddress, PageInfo, error)
 
-	GetEmailAddressesForAccount(ctx context.Context, accountID string, cursorRequest paginationV1.CursorRequest) ([]*accountV1.EmailAddress, error)
+	GetEmailAddressesForAccount(ctx context.Context, accountID string, page PageRequest) ([]*accountV1.EmailAddress, PageInfo, error)
+	GetEmailAddressesForAccounts(ctx context.Context, accountIDs []string) (map[string][]*accountV1.EmailAddress, error)
 
 	EmailIsConfirmed(ctx context.Context, emailAddress string) (bool, error)
 	EmailExists(ctx context.Context, emailAddress string) (bool, error)
 	CountEmail(ctx context.Context, emailAddress string) (int, error)
 	GetConfirmedEmailAddress(ctx context.Context) (*accountV1.EmailAddress, error)
+
+	GetUncanonicalizedEmailAddresses(ctx context.Context, rules, after string, limit int) ([]entities.EmailAddress, error)
+	UpdateCanonicalEmailAddress(ctx context.Context, id, canonicalEmailAddress, rules string) error
+	PutEmailAddressConflict(ctx context.Context, c entities.EmailAddressConflict) error
+	DeleteEmailAddressConflict(ctx context.Context, emailAddressID string) error
 }
 
 type emailTxImpl struct {
@@ -36,10 +42,10 @@ type emailTxImpl struct {
 func (tx *emailTxImpl) CreateEmailAddress(ctx context.Context, e entities.EmailAddress) error {
 	query := `
 INSERT INTO email_address
- (id, account_id, email_address, confirmed, is_primary)
- VALUES($1, $2, $3, $4, $5)
+ (id, account_id, email_address, canonical_email_address, canonical_rules, confirmed, is_primary)
+ VALUES($1, $2, $3, $4, $5, $6, $7)
 `
-	_, err := tx.tx.Exec(ctx, query, e.ID, e.AccountID, e.EmailAddress, e.Confirmed, e.Primary)
+	_, err := tx.tx.Exec(ctx, query, e.ID, e.AccountID, e.EmailAddress, e.CanonicalEmailAddress, e.CanonicalRules, e.Confirmed, e.Primary)
 
 	return err
 }
@@ -58,13 +64,58 @@ func (tx *emailTxImpl) ConfirmEmailAddress(ctx context.Context, id string) error
 	return err
 }
 
+// UpdatePrimaryEmailAddress makes the given email address the account's only
+// primary one, and points the account at it.
+func (tx *emailTxImpl) UpdatePrimaryEmailAddress(ctx context.Context, accountID, emailAddressID string) error {
+	now := time.Now()
+
+	// Clear the old primary before setting the new one, so that there is
+	// never more than one primary email address for the account.
+	query := `
+UPDATE email_address 
+ SET is_primary=FALSE, last_modified_at=$1 
+ WHERE account_id=$2 
+ AND is_primary=TRUE 
+ AND id <> $3
+`
+	if _, err := tx.tx.Exec(ctx, query, now, accountID, emailAddressID); err != nil {
+		return err
+	}
+
+	query = `
+UPDATE email_address 
+ SET is_primary=TRUE, last_modified_at=$1 
+ WHERE account_id=$2 
+ AND id=$3 
+ AND deleted_at IS NULL
+`
+	res, err := tx.tx.Exec(ctx, query, now, accountID, emailAddressID)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	// The account's version is left alone; callers bump it themselves
+	// as part of their optimistic concurrency check.
+	query = `
+UPDATE account 
+ SET primary_email_address_id=$1 
+ WHERE id=$2
+`
+	_, err = tx.tx.Exec(ctx, query, emailAddressID, accountID)
+	return err
+}
+
+// GetEmailAddressByEmailAddress looks up an email address by its canonical form.
 func (tx *emailTxImpl) GetEmailAddressByEmailAddress(ctx context.Context, emailAddress string) (*accountV1.EmailAddress, error) {
 	var e entities.EmailAddress
 
 	query := `
 SELECT id, created_at, last_modified_at, deleted_at, confirmed, is_primary, email_address, account_id 
  FROM email_address
- WHERE email_address=$1 
+ WHERE canonical_email_address=$1 
  AND deleted_at IS NULL
 `
 
@@ -87,7 +138,7 @@ func (tx *emailTxImpl) GetEmailAddressByID(ctx context.Context, id string) (*acc
 	row := tx.tx.QueryRow(
 		ctx,
 		"SELECT id, created_at, last_modified_at, deleted_at, confirmed, is_primary, email_address, account_id "+
-			"FROM phone_number WHERE id=$1 "+
+			"FROM email_address WHERE id=$1 "+
 			"AND deleted_at IS NULL", id)
 	err := row.Scan(&e.ID, &e.CreatedAt, &e.LastModifiedAt, &e.DeletedAt, &e.Confirmed, &e.Primary, &e.EmailAddress, &e.AccountID)
 
@@ -101,96 +152,147 @@ func (tx *emailTxImpl) GetEmailAddressByID(ctx context.Context, id string) (*acc
 	return e.ToProtobuf(), nil
 }
 
-func (tx *emailTxImpl) GetEmailAddresses(ctx context.Context, request paginationV1.CursorRequest) ([]*accountV1.EmailAddress, error) {
-	var sortString string
-	if len(request.SortClauses) == 0 {
-		sortString = "id ASC"
-	} else {
-		sortString = buildOrderByClause(request)
+func (tx *emailTxImpl) GetEmailAddresses(ctx context.Context, filter EmailAddressFilter, page PageRequest) ([]*accountV1.EmailAddress, PageInfo, error) {
+	where := filter.conditions()
+
+	after, args, orderBy, selectKey, err := emailAddressSortColumns.pageClauses(page, where.nextArg())
+	if err != nil {
+		return nil, PageInfo{}, err
 	}
 
 	queryTemplate := `
-SELECT id, created_at, last_modified_at, deleted_at, confirmed, email_address, account_id 
+SELECT id, created_at, last_modified_at, deleted_at, confirmed, is_primary, email_address, account_id, %s 
  FROM email_address
- WHERE id > $1
- AND deleted_at IS NULL
+ WHERE %s
+ AND %s
  ORDER BY %s
  FETCH FIRST %d ROWS ONLY
 `
 
-	query := fmt.Sprintf(queryTemplate, sortString, request.Count)
+	query := fmt.Sprintf(queryTemplate, selectKey, where, after, orderBy, page.fetchLimit())
 
-	rows, err := tx.tx.Query(ctx, query, request.Cursor)
+	rows, err := tx.tx.Query(ctx, query, append(where.args, args...)...)
 
 	if err != nil {
-		return nil, err
+		return nil, PageInfo{}, err
 	}
 
 	defer rows.Close()
 
 	emailAddresses := []*accountV1.EmailAddress{}
+	var keys []PageKey
 
 	for rows.Next() {
 		var e entities.EmailAddress
-		if err := rows.Scan(&e.ID, &e.CreatedAt, &e.LastModifiedAt, &e.DeletedAt, &e.Confirmed, &e.EmailAddress, &e.AccountID); err != nil {
-			return nil, err
+		var key PageKey
+		if err := rows.Scan(&e.ID, &e.CreatedAt, &e.LastModifiedAt, &e.DeletedAt, &e.Confirmed, &e.Primary, &e.EmailAddress, &e.AccountID, &key); err != nil {
+			return nil, PageInfo{}, err
 		}
 		emailAddresses = append(emailAddresses, e.ToProtobuf())
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, PageInfo{}, err
 	}
 
-	return emailAddresses, nil
+	info, n := page.pageInfo(keys)
+	emailAddresses = emailAddresses[:n]
+	if page.Backward {
+		reverseRows(emailAddresses)
+	}
+
+	return emailAddresses, info, nil
 }
 
-func (tx *emailTxImpl) GetEmailAddressesForAccount(ctx context.Context, accountID string, cursorRequest paginationV1.CursorRequest) ([]*accountV1.EmailAddress, error) {
-	var sortString string
-	if len(cursorRequest.SortClauses) == 0 {
-		sortString = "id ASC"
-	} else {
-		sortString = buildOrderByClause(cursorRequest)
+func (tx *emailTxImpl) GetEmailAddressesForAccount(ctx context.Context, accountID string, page PageRequest) ([]*accountV1.EmailAddress, PageInfo, error) {
+	after, args, orderBy, selectKey, err := emailAddressSortColumns.pageClauses(page, 2)
+	if err != nil {
+		return nil, PageInfo{}, err
 	}
 
 	queryTemplate := `
-SELECT id, email_address, account_id 
+SELECT id, created_at, last_modified_at, deleted_at, confirmed, is_primary, email_address, account_id, %s 
  FROM email_address 
  WHERE confirmed=TRUE 
  AND account_id=$1 
  AND deleted_at IS NULL 
- AND id > $2
+ AND %s
  ORDER BY %s 
  FETCH FIRST %d ROWS ONLY
 `
 
-	query := fmt.Sprintf(queryTemplate, sortString, cursorRequest.Count)
-	rows, err := tx.tx.Query(ctx, query, accountID, cursorRequest.Cursor)
+	query := fmt.Sprintf(queryTemplate, selectKey, after, orderBy, page.fetchLimit())
+	rows, err := tx.tx.Query(ctx, query, append([]interface{}{accountID}, args...)...)
 
 	if err != nil {
-		return nil, err
+		return nil, PageInfo{}, err
 	}
 
 	defer rows.Close()
 
 	emailAddresses := []*accountV1.EmailAddress{}
+	var keys []PageKey
 
 	for rows.Next() {
-		var e accountV1.EmailAddress
-		if err := rows.Scan(&e.Id, &e.EmailAddress, &e.AccountId); err != nil {
+		var e entities.EmailAddress
+		var key PageKey
+		if err := rows.Scan(&e.ID, &e.CreatedAt, &e.LastModifiedAt, &e.DeletedAt, &e.Confirmed, &e.Primary, &e.EmailAddress, &e.AccountID, &key); err != nil {
+			return nil, PageInfo{}, err
+		}
+		emailAddresses = append(emailAddresses, e.ToProtobuf())
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, PageInfo{}, err
+	}
+
+	info, n := page.pageInfo(keys)
+	emailAddresses = emailAddresses[:n]
+	if page.Backward {
+		reverseRows(emailAddresses)
+	}
+
+	return emailAddresses, info, nil
+}
+
+// GetEmailAddressesForAccounts loads the email addresses of many accounts
+// in one query, keyed by account ID, oldest first.
+func (tx *emailTxImpl) GetEmailAddressesForAccounts(ctx context.Context, accountIDs []string) (map[string][]*accountV1.EmailAddress, error) {
+	query := `
+SELECT id, created_at, last_modified_at, deleted_at, confirmed, is_primary, email_address, account_id 
+ FROM email_address 
+ WHERE account_id = ANY($1) 
+ AND deleted_at IS NULL 
+ ORDER BY created_at, id
+`
+	rows, err := tx.tx.Query(ctx, query, accountIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	out := make(map[string][]*accountV1.EmailAddress, len(accountIDs))
+
+	for rows.Next() {
+		var e entities.EmailAddress
+		if err := rows.Scan(&e.ID, &e.CreatedAt, &e.LastModifiedAt, &e.DeletedAt, &e.Confirmed, &e.Primary, &e.EmailAddress, &e.AccountID); err != nil {
 			return nil, err
 		}
-		// TODO do masking in service layer, not db layer
-		e.EmailAddress = maskEmail(e.EmailAddress)
-		emailAddresses = append(emailAddresses, &e)
+		out[e.AccountID] = append(out[e.AccountID], e.ToProtobuf())
 	}
 
-	return emailAddresses, nil
+	return out, rows.Err()
 }
 
+// EmailIsConfirmed reports whether the email address with the given canonical form is confirmed.
 func (tx *emailTxImpl) EmailIsConfirmed(ctx context.Context, emailAddress string) (bool, error) {
 	var count int
 
 	query := `
 SELECT COUNT(*) AS count 
  FROM email_address 
- WHERE email_address = $1
+ WHERE canonical_email_address = $1
  AND confirmed = $2
  AND deleted_at IS NULL
 `
@@ -216,13 +318,14 @@ func (tx *emailTxImpl) EmailExists(ctx context.Context, emailAddress string) (bo
 	return count == 1, nil
 }
 
+// CountEmail counts the live email addresses with the given canonical form.
 func (tx *emailTxImpl) CountEmail(ctx context.Context, emailAddress string) (int, error) {
 	var count int
 
 	query := `
 SELECT COUNT(*) AS count 
  FROM email_address 
- WHERE email_address=$1 
+ WHERE canonical_email_address=$1 
  AND deleted_at IS NULL
 `
 
@@ -255,23 +358,67 @@ SELECT id, email_address, account_id
 	return e.ToProtobuf(), nil
 }
 
-func maskEmail(emailAddress string) string {
-	return getMaskedEmailUser(emailAddress) + "@" + getMaskedEmailHost(emailAddress)
-}
+// GetUncanonicalizedEmailAddresses returns email addresses, deleted or not,
+// whose canonical form wasn't worked out with the given rules.
+// They're ordered by ID, starting after the given one.
+func (tx *emailTxImpl) GetUncanonicalizedEmailAddresses(ctx context.Context, rules, after string, limit int) ([]entities.EmailAddress, error) {
+	query := `
+SELECT id, deleted_at, email_address, canonical_email_address, canonical_rules, account_id
+ FROM email_address 
+ WHERE id > $1 
+ AND canonical_rules IS DISTINCT FROM $2 
+ ORDER BY id 
+ FETCH FIRST $3 ROWS ONLY
+`
+	rows, err := tx.tx.Query(ctx, query, after, rules, limit)
+	if err != nil {
+		return nil, err
+	}
 
-func getMaskedEmailUser(emailAddress string) string {
-	splits := strings.Split(emailAddress, "@")
-	user := splits[0]
-	if len(user) == 1 {
-		return user[0:1] + strings.Repeat("*", len(user)-1)
+	defer rows.Close()
+
+	emailAddresses := []entities.EmailAddress{}
+
+	for rows.Next() {
+		var e entities.EmailAddress
+		if err := rows.Scan(&e.ID, &e.DeletedAt, &e.EmailAddress, &e.CanonicalEmailAddress, &e.CanonicalRules, &e.AccountID); err != nil {
+			return nil, err
+		}
+		emailAddresses = append(emailAddresses, e)
 	}
-	return user[0:2] + strings.Repeat("*", len(user)-2)
+
+	return emailAddresses, rows.Err()
 }
 
-func getMaskedEmailHost(emailAddress string) string {
-	emailSplits := strings.Split(emailAddress, "@")
-	host := emailSplits[1]
-	splits := strings.Split(host, ".")
-	splits[0] = splits[0][0:1] + strings.Repeat("*", len(splits[0])-1)
-	return strings.Join(splits, ".")
+// UpdateCanonicalEmailAddress stores an email address's canonical form,
+// and the rules it was worked out with.
+func (tx *emailTxImpl) UpdateCanonicalEmailAddress(ctx context.Context, id, canonicalEmailAddress, rules string) error {
+	query := `
+UPDATE email_address 
+ SET canonical_email_address=$1, canonical_rules=$2 
+ WHERE id=$3
+`
+	_, err := tx.tx.Exec(ctx, query, canonicalEmailAddress, rules, id)
+	return err
+}
+
+// PutEmailAddressConflict records a conflict, replacing any recorded
+// for the same email address before.
+func (tx *emailTxImpl) PutEmailAddressConflict(ctx context.Context, c entities.EmailAddressConflict) error {
+	query := `
+INSERT INTO email_address_conflict
+ (email_address_id, account_id, canonical_email_address, conflicting_email_address_id, conflicting_account_id, detected_at)
+ VALUES($1, $2, $3, $4, $5, $6)
+ ON CONFLICT (email_address_id) DO UPDATE 
+ SET account_id=EXCLUDED.account_id, canonical_email_address=EXCLUDED.canonical_email_address, 
+ conflicting_email_address_id=EXCLUDED.conflicting_email_address_id, conflicting_account_id=EXCLUDED.conflicting_account_id, 
+ detected_at=EXCLUDED.detected_at
+`
+	_, err := tx.tx.Exec(ctx, query, c.EmailAddressID, c.AccountID, c.CanonicalEmailAddress, c.ConflictingEmailAddressID, c.ConflictingAccountID, c.DetectedAt)
+	return err
+}
+
+func (tx *emailTxImpl) DeleteEmailAddressConflict(ctx context.Context, emailAddressID string) error {
+	_, err := tx.tx.Exec(ctx, "DELETE FROM email_address_conflict WHERE email_address_id=$1", emailAddressID)
+	return err
 }
@@ -0,0 +1,81 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/AlpacaLabs/api-account/internal/db/entities"
+	"github.com/jackc/pgx/v4"
+)
+
+type EmailConfirmationTransaction interface {
+	CreateEmailConfirmationToken(ctx context.Context, t entities.EmailConfirmationToken) error
+	GetEmailConfirmationToken(ctx context.Context, id string) (*entities.EmailConfirmationToken, error)
+	ConsumeEmailConfirmationToken(ctx context.Context, id string) (int, error)
+	DeleteUnconsumedEmailConfirmationTokens(ctx context.Context, emailAddressID string) error
+}
+
+type emailConfirmationTxImpl struct {
+	tx pgx.Tx
+}
+
+func (tx *emailConfirmationTxImpl) CreateEmailConfirmationToken(ctx context.Context, t entities.EmailConfirmationToken) error {
+	query := `
+INSERT INTO email_address_confirmation_token
+ (id, created_at, email_address_id, token_hash, expires_at)
+ VALUES($1, $2, $3, $4, $5)
+`
+	_, err := tx.tx.Exec(ctx, query, t.ID, t.CreatedAt, t.EmailAddressID, t.TokenHash, t.ExpiresAt)
+	return err
+}
+
+func (tx *emailConfirmationTxImpl) GetEmailConfirmationToken(ctx context.Context, id string) (*entities.EmailConfirmationToken, error) {
+	var t entities.EmailConfirmationToken
+
+	query := `
+SELECT id, created_at, email_address_id, token_hash, expires_at, consumed_at 
+ FROM email_address_confirmation_token 
+ WHERE id=$1
+`
+
+	row := tx.tx.QueryRow(ctx, query, id)
+	err := row.Scan(&t.ID, &t.CreatedAt, &t.EmailAddressID, &t.TokenHash, &t.ExpiresAt, &t.ConsumedAt)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+// ConsumeEmailConfirmationToken marks a token as used. It returns 0 if the
+// token had already been consumed, so concurrent requests can't both use it.
+func (tx *emailConfirmationTxImpl) ConsumeEmailConfirmationToken(ctx context.Context, id string) (int, error) {
+	query := `
+UPDATE email_address_confirmation_token 
+ SET consumed_at=$1 
+ WHERE id=$2 
+ AND consumed_at IS NULL
+`
+	res, err := tx.tx.Exec(ctx, query, time.Now(), id)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(res.RowsAffected()), nil
+}
+
+// DeleteUnconsumedEmailConfirmationTokens invalidates every outstanding
+// token for an email address, e.g. when a fresh one is being sent.
+func (tx *emailConfirmationTxImpl) DeleteUnconsumedEmailConfirmationTokens(ctx context.Context, emailAddressID string) error {
+	query := `
+DELETE FROM email_address_confirmation_token 
+ WHERE email_address_id=$1 
+ AND consumed_at IS NULL
+`
+	_, err := tx.tx.Exec(ctx, query, emailAddressID)
+	return err
+}
@@ -0,0 +1,66 @@
+package db
+
+import (
+	"context"
+
+	"github.com/AlpacaLabs/api-account/internal/db/entities"
+	"github.com/jackc/pgx/v4"
+)
+
+type EmailDomainTransaction interface {
+	GetEmailDomainRules(ctx context.Context) ([]entities.EmailDomainRule, error)
+	PutEmailDomainRule(ctx context.Context, r entities.EmailDomainRule) error
+	DeleteEmailDomainRule(ctx context.Context, domain string) (int, error)
+}
+
+type emailDomainTxImpl struct {
+	tx pgx.Tx
+}
+
+func (tx *emailDomainTxImpl) GetEmailDomainRules(ctx context.Context) ([]entities.EmailDomainRule, error) {
+	query := `
+SELECT domain, action, reason, created_at, created_by
+ FROM email_domain_rule
+ ORDER BY domain
+`
+	rows, err := tx.tx.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	rules := []entities.EmailDomainRule{}
+
+	for rows.Next() {
+		var r entities.EmailDomainRule
+		if err := rows.Scan(&r.Domain, &r.Action, &r.Reason, &r.CreatedAt, &r.CreatedBy); err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+
+	return rules, rows.Err()
+}
+
+// PutEmailDomainRule creates the rule for a domain, or replaces the existing one.
+func (tx *emailDomainTxImpl) PutEmailDomainRule(ctx context.Context, r entities.EmailDomainRule) error {
+	query := `
+INSERT INTO email_domain_rule
+ (domain, action, reason, created_at, created_by)
+ VALUES($1, $2, $3, $4, $5)
+ ON CONFLICT (domain) DO UPDATE 
+ SET action=EXCLUDED.action, reason=EXCLUDED.reason, created_at=EXCLUDED.created_at, created_by=EXCLUDED.created_by
+`
+	_, err := tx.tx.Exec(ctx, query, r.Domain, r.Action, r.Reason, r.CreatedAt, r.CreatedBy)
+	return err
+}
+
+func (tx *emailDomainTxImpl) DeleteEmailDomainRule(ctx context.Context, domain string) (int, error) {
+	res, err := tx.tx.Exec(ctx, "DELETE FROM email_domain_rule WHERE domain=$1", domain)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(res.RowsAffected()), nil
+}
@@ -0,0 +1,114 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/AlpacaLabs/api-account/internal/db/entities"
+	"github.com/jackc/pgx/v4"
+)
+
+type OutboxTransaction interface {
+	CreateOutboxMessage(ctx context.Context, m entities.OutboxMessage) error
+	GetPendingOutboxMessages(ctx context.Context, limit int) ([]*entities.OutboxMessage, error)
+	MarkOutboxMessageSent(ctx context.Context, id int64) error
+	MarkOutboxMessageFailed(ctx context.Context, id int64, nextAttemptAt time.Time, reason string) error
+	DeleteSentOutboxMessages(ctx context.Context, sentBefore time.Time, limit int) (int, error)
+}
+
+type outboxTxImpl struct {
+	tx pgx.Tx
+}
+
+func (tx *outboxTxImpl) CreateOutboxMessage(ctx context.Context, m entities.OutboxMessage) error {
+	query := `
+INSERT INTO outbox
+ (created_at, topic, message_key, headers, payload, next_attempt_at)
+ VALUES($1, $2, $3, $4, $5, $6)
+`
+	_, err := tx.tx.Exec(ctx, query, m.CreatedAt, m.Topic, m.Key, m.Headers, m.Payload, m.NextAttemptAt)
+
+	return err
+}
+
+// GetPendingOutboxMessages locks and returns up to limit unsent messages
+// that are due for (re)delivery, oldest first.
+//
+// A message is skipped while an older message with the same topic and key
+// is still unsent, so that consumers see messages for a key in the order
+// they were written. Rows locked by other relays are skipped too, which
+// lets several instances of this service relay concurrently.
+func (tx *outboxTxImpl) GetPendingOutboxMessages(ctx context.Context, limit int) ([]*entities.OutboxMessage, error) {
+	query := `
+SELECT id, created_at, topic, message_key, headers, payload, attempts, next_attempt_at, last_error, sent_at 
+ FROM outbox o
+ WHERE o.sent_at IS NULL 
+ AND o.next_attempt_at <= $1
+ AND NOT EXISTS (
+   SELECT 1 FROM outbox p 
+    WHERE p.sent_at IS NULL 
+    AND p.topic = o.topic 
+    AND p.message_key = o.message_key 
+    AND p.id < o.id
+ )
+ ORDER BY o.id
+ LIMIT $2
+ FOR UPDATE SKIP LOCKED
+`
+
+	rows, err := tx.tx.Query(ctx, query, time.Now(), limit)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	messages := []*entities.OutboxMessage{}
+
+	for rows.Next() {
+		var m entities.OutboxMessage
+		if err := rows.Scan(&m.ID, &m.CreatedAt, &m.Topic, &m.Key, &m.Headers, &m.Payload,
+			&m.Attempts, &m.NextAttemptAt, &m.LastError, &m.SentAt); err != nil {
+			return nil, err
+		}
+		messages = append(messages, &m)
+	}
+
+	return messages, rows.Err()
+}
+
+func (tx *outboxTxImpl) MarkOutboxMessageSent(ctx context.Context, id int64) error {
+	_, err := tx.tx.Exec(ctx, "UPDATE outbox SET sent_at=$1, attempts=attempts+1 WHERE id=$2", time.Now(), id)
+	return err
+}
+
+func (tx *outboxTxImpl) MarkOutboxMessageFailed(ctx context.Context, id int64, nextAttemptAt time.Time, reason string) error {
+	query := `
+UPDATE outbox 
+ SET attempts=attempts+1, next_attempt_at=$1, last_error=$2 
+ WHERE id=$3
+`
+	_, err := tx.tx.Exec(ctx, query, nextAttemptAt, reason, id)
+	return err
+}
+
+// DeleteSentOutboxMessages deletes up to limit messages that were relayed
+// before the given time. It returns the number of messages deleted.
+func (tx *outboxTxImpl) DeleteSentOutboxMessages(ctx context.Context, sentBefore time.Time, limit int) (int, error) {
+	query := `
+DELETE FROM outbox 
+ WHERE id IN (
+   SELECT id FROM outbox 
+    WHERE sent_at < $1 
+    ORDER BY sent_at 
+    LIMIT $2 
+    FOR UPDATE SKIP LOCKED
+ )
+`
+	res, err := tx.tx.Exec(ctx, query, sentBefore, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(res.RowsAffected()), nil
+}
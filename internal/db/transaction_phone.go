@@ -6,7 +6,6 @@ import (
 
 	"github.com/AlpacaLabs/api-account/internal/db/entities"
 	accountV1 "github.com/AlpacaLabs/protorepo-account-go/alpacalabs/account/v1"
-	paginationV1 "github.com/AlpacaLabs/protorepo-pagination-go/alpacalabs/pagination/v1"
 	"github.com/jackc/pgx/v4"
 )
 
@@ -15,11 +14,16 @@ type PhoneTransaction interface {
 	DeletePhoneNumber(ctx context.Context, id string) (int, error)
 	ConfirmPhoneNumber(ctx context.Context, id string) error
 
-	GetPhoneNumbers(ctx context.Context, request paginationV1.CursorRequest) ([]*accountV1.PhoneNumber, error)
+	GetPhoneNumbers(ctx context.Context, filter PhoneNumberFilter, page PageRequest) ([]*accountV1.PhoneNumber, PageInfo, error)
 
 	GetPhoneNumberByID(ctx context.Context, id string) (*accountV1.PhoneNumber, error)
-	GetPhoneNumbersForAccount(ctx context.Context, accountID string, cursorRequest paginationV1.CursorRequest) ([]*accountV1.PhoneNumber, error)
+	GetPhoneNumbersForAccount(ctx context.Context, accountID string, page PageRequest) ([]*accountV1.PhoneNumber, PageInfo, error)
+	GetPhoneNumbersForAccounts(ctx context.Context, accountIDs []string) (map[string][]*accountV1.PhoneNumber, error)
 	GetPhoneNumberByPhoneNumber(ctx context.Context, phoneNumber string) (*accountV1.PhoneNumber, error)
+
+	GetUnparsedPhoneNumbers(ctx context.Context, limit int) ([]entities.PhoneNumber, error)
+	UpdateParsedPhoneNumber(ctx context.Context, p entities.PhoneNumber) error
+	PutPhoneNumberConflict(ctx context.Context, c entities.PhoneNumberConflict) error
 }
 
 type phoneTxImpl struct {
@@ -29,10 +33,10 @@ type phoneTxImpl struct {
 func (tx *phoneTxImpl) CreatePhoneNumber(ctx context.Context, e entities.PhoneNumber) error {
 	query := `
 INSERT INTO phone_number
- (id, account_id, phone_number, confirmed)
- VALUES($1, $2, $3, $4)
+ (id, account_id, phone_number, confirmed, country_code, number_type)
+ VALUES($1, $2, $3, $4, $5, $6)
 `
-	_, err := tx.tx.Exec(ctx, query, e.ID, e.AccountID, e.PhoneNumber, e.Confirmed)
+	_, err := tx.tx.Exec(ctx, query, e.ID, e.AccountID, e.PhoneNumber, e.Confirmed, e.CountryCode, e.NumberType)
 
 	return err
 }
@@ -51,44 +55,56 @@ func (tx *phoneTxImpl) ConfirmPhoneNumber(ctx context.Context, id string) error
 	return err
 }
 
-func (tx *phoneTxImpl) GetPhoneNumbers(ctx context.Context, request paginationV1.CursorRequest) ([]*accountV1.PhoneNumber, error) {
-	var sortString string
-	if len(request.SortClauses) == 0 {
-		sortString = "id ASC"
-	} else {
-		sortString = buildOrderByClause(request)
+func (tx *phoneTxImpl) GetPhoneNumbers(ctx context.Context, filter PhoneNumberFilter, page PageRequest) ([]*accountV1.PhoneNumber, PageInfo, error) {
+	where := filter.conditions()
+
+	after, args, orderBy, selectKey, err := phoneNumberSortColumns.pageClauses(page, where.nextArg())
+	if err != nil {
+		return nil, PageInfo{}, err
 	}
 
 	queryTemplate := `
-SELECT id, created_at, last_modified_at, deleted_at, confirmed, phone_number, account_id 
+SELECT id, created_at, last_modified_at, deleted_at, confirmed, phone_number, account_id, %s 
  FROM phone_number
- WHERE id > $1
- AND deleted_at IS NULL
+ WHERE %s
+ AND %s
  ORDER BY %s
  FETCH FIRST %d ROWS ONLY
 `
 
-	query := fmt.Sprintf(queryTemplate, sortString, request.Count)
+	query := fmt.Sprintf(queryTemplate, selectKey, where, after, orderBy, page.fetchLimit())
 
-	rows, err := tx.tx.Query(ctx, query, request.Cursor)
+	rows, err := tx.tx.Query(ctx, query, append(where.args, args...)...)
 
 	if err != nil {
-		return nil, err
+		return nil, PageInfo{}, err
 	}
 
 	defer rows.Close()
 
 	phoneNumbers := []*accountV1.PhoneNumber{}
+	var keys []PageKey
 
 	for rows.Next() {
 		var e entities.PhoneNumber
-		if err := rows.Scan(&e.ID, &e.CreatedAt, &e.LastModifiedAt, &e.DeletedAt, &e.Confirmed, &e.PhoneNumber, &e.AccountID); err != nil {
-			return nil, err
+		var key PageKey
+		if err := rows.Scan(&e.ID, &e.CreatedAt, &e.LastModifiedAt, &e.DeletedAt, &e.Confirmed, &e.PhoneNumber, &e.AccountID, &key); err != nil {
+			return nil, PageInfo{}, err
 		}
 		phoneNumbers = append(phoneNumbers, e.ToProtobuf())
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, PageInfo{}, err
 	}
 
-	return phoneNumbers, nil
+	info, n := page.pageInfo(keys)
+	phoneNumbers = phoneNumbers[:n]
+	if page.Backward {
+		reverseRows(phoneNumbers)
+	}
+
+	return phoneNumbers, info, nil
 }
 
 func (tx *phoneTxImpl) GetPhoneNumberByID(ctx context.Context, id string) (*accountV1.PhoneNumber, error) {
@@ -113,45 +129,54 @@ SELECT id, created_at, last_modified_at, deleted_at, confirmed, phone_number, ac
 	return p.ToProtobuf(), nil
 }
 
-func (tx *phoneTxImpl) GetPhoneNumbersForAccount(ctx context.Context, accountID string, cursorRequest paginationV1.CursorRequest) ([]*accountV1.PhoneNumber, error) {
-	var sortString string
-	if len(cursorRequest.SortClauses) == 0 {
-		sortString = "id ASC"
-	} else {
-		sortString = buildOrderByClause(cursorRequest)
+func (tx *phoneTxImpl) GetPhoneNumbersForAccount(ctx context.Context, accountID string, page PageRequest) ([]*accountV1.PhoneNumber, PageInfo, error) {
+	after, args, orderBy, selectKey, err := phoneNumberSortColumns.pageClauses(page, 2)
+	if err != nil {
+		return nil, PageInfo{}, err
 	}
 
 	queryTemplate := `
-SELECT id, phone_number, account_id, created_at, confirmed
+SELECT id, created_at, last_modified_at, deleted_at, confirmed, phone_number, account_id, %s
  FROM phone_number 
  WHERE confirmed=TRUE 
  AND account_id=$1 
  AND deleted_at IS NULL 
- AND id > $2
+ AND %s
  ORDER BY %s 
  FETCH FIRST %d ROWS ONLY
 `
-	query := fmt.Sprintf(queryTemplate, sortString, cursorRequest.Count)
-	rows, err := tx.tx.Query(ctx, query, accountID, cursorRequest.Cursor)
+	query := fmt.Sprintf(queryTemplate, selectKey, after, orderBy, page.fetchLimit())
+	rows, err := tx.tx.Query(ctx, query, append([]interface{}{accountID}, args...)...)
 
 	if err != nil {
-		return nil, err
+		return nil, PageInfo{}, err
 	}
 
 	defer rows.Close()
 
 	phoneNumbers := []*accountV1.PhoneNumber{}
+	var keys []PageKey
 
 	for rows.Next() {
 		var p entities.PhoneNumber
-		if err := rows.Scan(&p.ID, &p.PhoneNumber, &p.AccountID, &p.CreatedAt, &p.Confirmed); err != nil {
-			return nil, err
+		var key PageKey
+		if err := rows.Scan(&p.ID, &p.CreatedAt, &p.LastModifiedAt, &p.DeletedAt, &p.Confirmed, &p.PhoneNumber, &p.AccountID, &key); err != nil {
+			return nil, PageInfo{}, err
 		}
-		p.PhoneNumber = maskPhoneNumber(p.PhoneNumber)
 		phoneNumbers = append(phoneNumbers, p.ToProtobuf())
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, PageInfo{}, err
 	}
 
-	return phoneNumbers, nil
+	info, n := page.pageInfo(keys)
+	phoneNumbers = phoneNumbers[:n]
+	if page.Backward {
+		reverseRows(phoneNumbers)
+	}
+
+	return phoneNumbers, info, nil
 }
 
 func (tx *phoneTxImpl) GetPhoneNumberByPhoneNumber(ctx context.Context, phoneNumber string) (*accountV1.PhoneNumber, error) {
@@ -175,6 +200,89 @@ SELECT id, phone_number, account_id, created_at, confirmed
 	return p.ToProtobuf(), nil
 }
 
-func maskPhoneNumber(phoneNumber string) string {
-	return phoneNumber[len(phoneNumber)-2:]
+// GetPhoneNumbersForAccounts loads the phone numbers of many accounts
+// in one query, keyed by account ID, oldest first.
+func (tx *phoneTxImpl) GetPhoneNumbersForAccounts(ctx context.Context, accountIDs []string) (map[string][]*accountV1.PhoneNumber, error) {
+	query := `
+SELECT id, created_at, last_modified_at, deleted_at, confirmed, phone_number, account_id
+ FROM phone_number 
+ WHERE account_id = ANY($1) 
+ AND deleted_at IS NULL 
+ ORDER BY created_at, id
+`
+	rows, err := tx.tx.Query(ctx, query, accountIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	out := make(map[string][]*accountV1.PhoneNumber, len(accountIDs))
+
+	for rows.Next() {
+		var p entities.PhoneNumber
+		if err := rows.Scan(&p.ID, &p.CreatedAt, &p.LastModifiedAt, &p.DeletedAt, &p.Confirmed, &p.PhoneNumber, &p.AccountID); err != nil {
+			return nil, err
+		}
+		out[p.AccountID] = append(out[p.AccountID], p.ToProtobuf())
+	}
+
+	return out, rows.Err()
+}
+
+// GetUnparsedPhoneNumbers returns phone numbers stored before E.164
+// normalization, which have yet to be parsed.
+func (tx *phoneTxImpl) GetUnparsedPhoneNumbers(ctx context.Context, limit int) ([]entities.PhoneNumber, error) {
+	query := `
+SELECT id, created_at, last_modified_at, deleted_at, confirmed, phone_number, account_id
+ FROM phone_number 
+ WHERE number_type IS NULL 
+ ORDER BY id 
+ FETCH FIRST $1 ROWS ONLY
+`
+	rows, err := tx.tx.Query(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	phoneNumbers := []entities.PhoneNumber{}
+
+	for rows.Next() {
+		var p entities.PhoneNumber
+		if err := rows.Scan(&p.ID, &p.CreatedAt, &p.LastModifiedAt, &p.DeletedAt, &p.Confirmed, &p.PhoneNumber, &p.AccountID); err != nil {
+			return nil, err
+		}
+		phoneNumbers = append(phoneNumbers, p)
+	}
+
+	return phoneNumbers, rows.Err()
+}
+
+// UpdateParsedPhoneNumber stores what a phone number parsed as.
+func (tx *phoneTxImpl) UpdateParsedPhoneNumber(ctx context.Context, p entities.PhoneNumber) error {
+	query := `
+UPDATE phone_number 
+ SET phone_number=$1, country_code=$2, number_type=$3 
+ WHERE id=$4
+`
+	_, err := tx.tx.Exec(ctx, query, p.PhoneNumber, p.CountryCode, p.NumberType, p.ID)
+	return err
+}
+
+// PutPhoneNumberConflict records a conflict, replacing any recorded
+// for the same phone number before.
+func (tx *phoneTxImpl) PutPhoneNumberConflict(ctx context.Context, c entities.PhoneNumberConflict) error {
+	query := `
+INSERT INTO phone_number_conflict
+ (phone_number_id, account_id, e164_phone_number, conflicting_phone_number_id, conflicting_account_id, detected_at)
+ VALUES($1, $2, $3, $4, $5, $6)
+ ON CONFLICT (phone_number_id) DO UPDATE 
+ SET account_id=EXCLUDED.account_id, e164_phone_number=EXCLUDED.e164_phone_number, 
+ conflicting_phone_number_id=EXCLUDED.conflicting_phone_number_id, conflicting_account_id=EXCLUDED.conflicting_account_id, 
+ detected_at=EXCLUDED.detected_at
+`
+	_, err := tx.tx.Exec(ctx, query, c.PhoneNumberID, c.AccountID, c.E164PhoneNumber, c.ConflictingPhoneNumberID, c.ConflictingAccountID, c.DetectedAt)
+	return err
 }
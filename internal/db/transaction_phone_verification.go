@@ -0,0 +1,74 @@
+package db
+
+import (
+	"context"
+
+	"github.com/AlpacaLabs/api-account/internal/db/entities"
+	"github.com/jackc/pgx/v4"
+)
+
+type PhoneVerificationTransaction interface {
+	CreatePhoneNumberVerification(ctx context.Context, v entities.PhoneNumberVerification) error
+	GetPhoneNumberVerification(ctx context.Context, phoneNumberID string) (*entities.PhoneNumberVerification, error)
+	IncrementPhoneNumberVerificationAttempts(ctx context.Context, phoneNumberID string) error
+	ResetPhoneNumberVerificationAttempts(ctx context.Context, phoneNumberID string) error
+	DeletePhoneNumberVerification(ctx context.Context, phoneNumberID string) error
+}
+
+type phoneVerificationTxImpl struct {
+	tx pgx.Tx
+}
+
+// CreatePhoneNumberVerification stores a new passcode for a phone number,
+// replacing any previous one. Wrong attempts at the previous one still count.
+func (tx *phoneVerificationTxImpl) CreatePhoneNumberVerification(ctx context.Context, v entities.PhoneNumberVerification) error {
+	query := `
+INSERT INTO phone_number_verification
+ (phone_number_id, created_at, code_hash, expires_at, attempts)
+ VALUES($1, $2, $3, $4, 0)
+ ON CONFLICT (phone_number_id) DO UPDATE 
+ SET created_at=EXCLUDED.created_at, code_hash=EXCLUDED.code_hash, expires_at=EXCLUDED.expires_at
+`
+	_, err := tx.tx.Exec(ctx, query, v.PhoneNumberID, v.CreatedAt, v.CodeHash, v.ExpiresAt)
+	return err
+}
+
+// GetPhoneNumberVerification locks and returns the pending passcode for a
+// phone number, so concurrent guesses are counted one at a time.
+func (tx *phoneVerificationTxImpl) GetPhoneNumberVerification(ctx context.Context, phoneNumberID string) (*entities.PhoneNumberVerification, error) {
+	var v entities.PhoneNumberVerification
+
+	query := `
+SELECT phone_number_id, created_at, code_hash, expires_at, attempts, last_attempt_at 
+ FROM phone_number_verification 
+ WHERE phone_number_id=$1 
+ FOR UPDATE
+`
+
+	row := tx.tx.QueryRow(ctx, query, phoneNumberID)
+	err := row.Scan(&v.PhoneNumberID, &v.CreatedAt, &v.CodeHash, &v.ExpiresAt, &v.Attempts, &v.LastAttemptAt)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &v, nil
+}
+
+func (tx *phoneVerificationTxImpl) IncrementPhoneNumberVerificationAttempts(ctx context.Context, phoneNumberID string) error {
+	_, err := tx.tx.Exec(ctx, "UPDATE phone_number_verification SET attempts=attempts+1, last_attempt_at=now() WHERE phone_number_id=$1", phoneNumberID)
+	return err
+}
+
+func (tx *phoneVerificationTxImpl) ResetPhoneNumberVerificationAttempts(ctx context.Context, phoneNumberID string) error {
+	_, err := tx.tx.Exec(ctx, "UPDATE phone_number_verification SET attempts=0 WHERE phone_number_id=$1", phoneNumberID)
+	return err
+}
+
+func (tx *phoneVerificationTxImpl) DeletePhoneNumberVerification(ctx context.Context, phoneNumberID string) error {
+	_, err := tx.tx.Exec(ctx, "DELETE FROM phone_number_verification WHERE phone_number_id=$1", phoneNumberID)
+	return err
+}
@@ -0,0 +1,121 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/AlpacaLabs/api-account/internal/db/entities"
+	"github.com/jackc/pgx/v4"
+)
+
+type UsernameHistoryTransaction interface {
+	CreateUsernameChange(ctx context.Context, c entities.UsernameChange) error
+	GetLatestUsernameChange(ctx context.Context, accountID string) (*entities.UsernameChange, error)
+	GetUsernameChangeByUsername(ctx context.Context, username string, changedAfter time.Time) (*entities.UsernameChange, error)
+	GetUsernameChangeBySkeleton(ctx context.Context, skeleton string, changedAfter time.Time, excludeAccountID string) (*entities.UsernameChange, error)
+	GetUsernameChangesByUsernames(ctx context.Context, usernames []string, changedAfter time.Time) ([]*entities.UsernameChange, error)
+}
+
+type usernameHistoryTxImpl struct {
+	tx pgx.Tx
+}
+
+func (tx *usernameHistoryTxImpl) CreateUsernameChange(ctx context.Context, c entities.UsernameChange) error {
+	query := `
+INSERT INTO username_history
+ (id, account_id, username, username_skeleton, changed_at)
+ VALUES($1, $2, $3, $4, $5)
+`
+	_, err := tx.tx.Exec(ctx, query, c.ID, c.AccountID, c.Username, c.UsernameSkeleton, c.ChangedAt)
+	return err
+}
+
+// GetLatestUsernameChange returns the most recent time the account
+// changed its username.
+func (tx *usernameHistoryTxImpl) GetLatestUsernameChange(ctx context.Context, accountID string) (*entities.UsernameChange, error) {
+	query := `
+SELECT id, account_id, username, username_skeleton, changed_at
+ FROM username_history
+ WHERE account_id=$1
+ ORDER BY changed_at DESC
+ LIMIT 1
+`
+	return tx.getUsernameChange(ctx, query, accountID)
+}
+
+// GetUsernameChangeByUsername returns the most recent change away from
+// exactly this username since changedAfter.
+func (tx *usernameHistoryTxImpl) GetUsernameChangeByUsername(ctx context.Context, username string, changedAfter time.Time) (*entities.UsernameChange, error) {
+	query := `
+SELECT id, account_id, username, username_skeleton, changed_at
+ FROM username_history
+ WHERE username=$1
+ AND changed_at > $2
+ ORDER BY changed_at DESC
+ LIMIT 1
+`
+	return tx.getUsernameChange(ctx, query, username, changedAfter)
+}
+
+// GetUsernameChangeBySkeleton returns the most recent change, by an account
+// other than excludeAccountID, away from a username that looks like the
+// given skeleton since changedAfter.
+func (tx *usernameHistoryTxImpl) GetUsernameChangeBySkeleton(ctx context.Context, skeleton string, changedAfter time.Time, excludeAccountID string) (*entities.UsernameChange, error) {
+	query := `
+SELECT id, account_id, username, username_skeleton, changed_at
+ FROM username_history
+ WHERE username_skeleton=$1
+ AND changed_at > $2
+ AND account_id <> $3
+ ORDER BY changed_at DESC
+ LIMIT 1
+`
+	return tx.getUsernameChange(ctx, query, skeleton, changedAfter, excludeAccountID)
+}
+
+// GetUsernameChangesByUsernames returns, for each of the given usernames
+// that was changed away from since changedAfter, the most recent such
+// change, in no particular order.
+func (tx *usernameHistoryTxImpl) GetUsernameChangesByUsernames(ctx context.Context, usernames []string, changedAfter time.Time) ([]*entities.UsernameChange, error) {
+	query := `
+SELECT DISTINCT ON (username) id, account_id, username, username_skeleton, changed_at
+ FROM username_history
+ WHERE username = ANY($1)
+ AND changed_at > $2
+ ORDER BY username, changed_at DESC
+`
+	rows, err := tx.tx.Query(ctx, query, usernames, changedAfter)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	changes := []*entities.UsernameChange{}
+
+	for rows.Next() {
+		var c entities.UsernameChange
+		if err := rows.Scan(&c.ID, &c.AccountID, &c.Username, &c.UsernameSkeleton, &c.ChangedAt); err != nil {
+			return nil, err
+		}
+		changes = append(changes, &c)
+	}
+
+	return changes, rows.Err()
+}
+
+func (tx *usernameHistoryTxImpl) getUsernameChange(ctx context.Context, query string, args ...interface{}) (*entities.UsernameChange, error) {
+	var c entities.UsernameChange
+
+	row := tx.tx.QueryRow(ctx, query, args...)
+	err := row.Scan(&c.ID, &c.AccountID, &c.Username, &c.UsernameSkeleton, &c.ChangedAt)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &c, nil
+}
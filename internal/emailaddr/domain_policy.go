@@ -0,0 +1,117 @@
+package emailaddr
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/AlpacaLabs/api-account/internal/db/entities"
+)
+
+//go:embed disposable_domains.txt
+var disposableDomainsFile string
+
+// DomainPolicy decides which domains email addresses may be registered at.
+// It combines a list of disposable domains with admin-managed rules,
+// both of which can be swapped out while it's in use.
+type DomainPolicy struct {
+	mu         sync.RWMutex
+	disposable map[string]bool
+	rules      map[string]entities.EmailDomainRule
+}
+
+// NewDomainPolicy returns a policy that blocks the disposable domains
+// built into the binary, until SetDisposableDomains replaces them.
+func NewDomainPolicy() *DomainPolicy {
+	return &DomainPolicy{
+		disposable: parseDomainList(disposableDomainsFile),
+		rules:      map[string]entities.EmailDomainRule{},
+	}
+}
+
+// SetDisposableDomains replaces the list of disposable domains,
+// given one per line with # comments.
+func (p *DomainPolicy) SetDisposableDomains(list string) {
+	disposable := parseDomainList(list)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.disposable = disposable
+}
+
+// SetRules replaces the admin-managed rules.
+func (p *DomainPolicy) SetRules(rules []entities.EmailDomainRule) {
+	m := make(map[string]entities.EmailDomainRule, len(rules))
+	for _, r := range rules {
+		m[r.Domain] = r
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rules = m
+}
+
+// PutRule adds or replaces a single rule.
+func (p *DomainPolicy) PutRule(rule entities.EmailDomainRule) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rules[rule.Domain] = rule
+}
+
+// DeleteRule removes the rule for a domain, if there is one.
+func (p *DomainPolicy) DeleteRule(domain string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.rules, domain)
+}
+
+// Check returns an error saying why email addresses at the given canonical
+// domain aren't allowed, or nil if they are. Rules for a domain cover its
+// subdomains too, and the rule for the most specific domain wins.
+func (p *DomainPolicy) Check(domain string) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for d := domain; d != ""; d = parentDomain(d) {
+		if r, ok := p.rules[d]; ok {
+			if r.Action == entities.EmailDomainActionAllow {
+				return nil
+			}
+			if r.Reason != "" {
+				return fmt.Errorf("email addresses at %s are not allowed: %s", domain, r.Reason)
+			}
+			return fmt.Errorf("email addresses at %s are not allowed", domain)
+		}
+		if p.disposable[d] {
+			return fmt.Errorf("email addresses at %s are not allowed: disposable email domains are blocked", domain)
+		}
+	}
+
+	return nil
+}
+
+// parentDomain strips the leftmost label off a domain, returning ""
+// once only a top-level domain would be left.
+func parentDomain(domain string) string {
+	i := strings.Index(domain, ".")
+	if i < 0 || !strings.Contains(domain[i+1:], ".") {
+		return ""
+	}
+	return domain[i+1:]
+}
+
+// parseDomainList reads one domain per line, skipping blanks and # comments.
+func parseDomainList(s string) map[string]bool {
+	domains := map[string]bool{}
+	scanner := bufio.NewScanner(strings.NewReader(s))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		domains[strings.ToLower(line)] = true
+	}
+	return domains
+}
@@ -0,0 +1,49 @@
+package emailaddr
+
+import (
+	"testing"
+
+	"github.com/AlpacaLabs/api-account/internal/db/entities"
+)
+
+func TestDomainPolicyCheck(t *testing.T) {
+	p := NewDomainPolicy()
+	p.SetDisposableDomains("# comment\n\nThrowaway.example\n")
+	p.SetRules([]entities.EmailDomainRule{
+		{Domain: "blocked.example", Action: entities.EmailDomainActionDeny},
+		{Domain: "ok.throwaway.example", Action: entities.EmailDomainActionAllow},
+	})
+
+	tests := []struct {
+		domain  string
+		allowed bool
+	}{
+		{"example.com", true},
+		{"throwaway.example", false},
+		{"mx.throwaway.example", false},
+		{"ok.throwaway.example", true},
+		{"blocked.example", false},
+		{"sub.blocked.example", false},
+		{"example", true},
+	}
+
+	for _, tt := range tests {
+		err := p.Check(tt.domain)
+		if got := err == nil; got != tt.allowed {
+			t.Errorf("Check(%q) error = %v, want allowed = %v", tt.domain, err, tt.allowed)
+		}
+	}
+}
+
+func TestSetDisposableDomainsReplacesList(t *testing.T) {
+	p := NewDomainPolicy()
+	p.SetDisposableDomains("first.example")
+	p.SetDisposableDomains("second.example")
+
+	if err := p.Check("first.example"); err != nil {
+		t.Errorf("Check(first.example) error = %v, want it allowed once dropped from the list", err)
+	}
+	if err := p.Check("second.example"); err == nil {
+		t.Error("Check(second.example) = nil, want it blocked")
+	}
+}
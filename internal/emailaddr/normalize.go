@@ -0,0 +1,138 @@
+package emailaddr
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/AlpacaLabs/api-account/internal/configuration"
+	"github.com/badoux/checkmail"
+	"golang.org/x/net/idna"
+)
+
+var ErrInvalidFormat = errors.New("invalid email address format")
+
+// Address is an email address in the two forms it's stored in.
+type Address struct {
+	// Display is what the user typed, tidied up: surrounding space is
+	// trimmed and the domain is lowercased. It's what we show and mail.
+	Display string
+
+	// Canonical identifies the inbox. Addresses that deliver to the same
+	// inbox have the same canonical form, which is what has to be unique.
+	Canonical string
+}
+
+// Domain returns the canonical domain of the address.
+func (a Address) Domain() string {
+	return a.Canonical[strings.LastIndex(a.Canonical, "@")+1:]
+}
+
+// provider holds the address rules of a mail provider.
+type provider struct {
+	// domain is the provider's main domain, which its aliases map to.
+	domain string
+
+	// ignoreDots is set if dots in the local part are ignored.
+	ignoreDots bool
+
+	// ignoreTags is set if anything after a plus in the local part is ignored.
+	ignoreTags bool
+}
+
+var gmail = provider{domain: "gmail.com", ignoreDots: true, ignoreTags: true}
+
+// providers are keyed by ASCII domain.
+var providers = map[string]provider{
+	"gmail.com":      gmail,
+	"googlemail.com": gmail,
+}
+
+// Normalizer works out the display and canonical forms of email addresses.
+type Normalizer struct {
+	providerRules bool
+}
+
+func NewNormalizer(config configuration.Config) Normalizer {
+	return Normalizer{
+		providerRules: config.EmailProviderRules,
+	}
+}
+
+// Rules names the rules canonical forms are worked out with.
+// Canonical forms stored under other rules need working out again.
+func (n Normalizer) Rules() string {
+	if n.providerRules {
+		return "provider"
+	}
+	return "default"
+}
+
+// Normalize validates an email address and returns its forms.
+// Internationalized domains may be given in Unicode or punycode;
+// the canonical form always uses punycode.
+func (n Normalizer) Normalize(emailAddress string) (Address, error) {
+	emailAddress = strings.TrimSpace(emailAddress)
+
+	i := strings.LastIndex(emailAddress, "@")
+	if i <= 0 {
+		return Address{}, ErrInvalidFormat
+	}
+	local, domain := emailAddress[:i], emailAddress[i+1:]
+
+	asciiDomain, err := idna.Lookup.ToASCII(domain)
+	if err != nil {
+		return Address{}, fmt.Errorf("%w: %v", ErrInvalidFormat, err)
+	}
+	unicodeDomain, err := idna.Lookup.ToUnicode(asciiDomain)
+	if err != nil {
+		return Address{}, fmt.Errorf("%w: %v", ErrInvalidFormat, err)
+	}
+
+	if err := checkmail.ValidateFormat(local + "@" + asciiDomain); err != nil {
+		return Address{}, ErrInvalidFormat
+	}
+
+	canonicalLocal := strings.ToLower(local)
+	canonicalDomain := asciiDomain
+	if p, ok := n.provider(asciiDomain); ok {
+		if p.ignoreTags {
+			if j := strings.Index(canonicalLocal, "+"); j >= 0 {
+				canonicalLocal = canonicalLocal[:j]
+			}
+		}
+		if p.ignoreDots {
+			canonicalLocal = strings.Replace(canonicalLocal, ".", "", -1)
+		}
+		if canonicalLocal == "" {
+			return Address{}, ErrInvalidFormat
+		}
+		canonicalDomain = p.domain
+	}
+
+	return Address{
+		Display:   local + "@" + unicodeDomain,
+		Canonical: canonicalLocal + "@" + canonicalDomain,
+	}, nil
+}
+
+// NormalizeDomain returns the canonical form of a bare domain,
+// as found after the @ in canonical email addresses.
+func (n Normalizer) NormalizeDomain(domain string) (string, error) {
+	asciiDomain, err := idna.Lookup.ToASCII(strings.TrimSpace(domain))
+	if err != nil {
+		return "", fmt.Errorf("invalid domain: %v", err)
+	}
+	if p, ok := n.provider(asciiDomain); ok {
+		return p.domain, nil
+	}
+	return asciiDomain, nil
+}
+
+func (n Normalizer) provider(asciiDomain string) (provider, bool) {
+	if !n.providerRules {
+		return provider{}, false
+	}
+	p, ok := providers[asciiDomain]
+	return p, ok
+}
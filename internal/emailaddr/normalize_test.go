@@ -0,0 +1,132 @@
+package emailaddr
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/AlpacaLabs/api-account/internal/configuration"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name          string
+		providerRules bool
+		in            string
+		wantDisplay   string
+		wantCanonical string
+		wantErr       bool
+	}{
+		{
+			name:          "lowercases the canonical form",
+			in:            "  Jane.Doe@Example.COM ",
+			wantDisplay:   "Jane.Doe@example.com",
+			wantCanonical: "jane.doe@example.com",
+		},
+		{
+			name:          "gmail dots and tags count without provider rules",
+			in:            "J.Doe+news@gmail.com",
+			wantDisplay:   "J.Doe+news@gmail.com",
+			wantCanonical: "j.doe+news@gmail.com",
+		},
+		{
+			name:          "gmail ignores dots",
+			providerRules: true,
+			in:            "j.d.o.e@gmail.com",
+			wantDisplay:   "j.d.o.e@gmail.com",
+			wantCanonical: "jdoe@gmail.com",
+		},
+		{
+			name:          "gmail ignores tags",
+			providerRules: true,
+			in:            "JDoe+news.letters@gmail.com",
+			wantDisplay:   "JDoe+news.letters@gmail.com",
+			wantCanonical: "jdoe@gmail.com",
+		},
+		{
+			name:          "googlemail is gmail",
+			providerRules: true,
+			in:            "j.doe@GoogleMail.com",
+			wantDisplay:   "j.doe@googlemail.com",
+			wantCanonical: "jdoe@gmail.com",
+		},
+		{
+			name:          "other providers keep dots and tags",
+			providerRules: true,
+			in:            "j.doe+news@example.com",
+			wantDisplay:   "j.doe+news@example.com",
+			wantCanonical: "j.doe+news@example.com",
+		},
+		{
+			name:          "unicode domains are canonically punycode",
+			in:            "jane@Bücher.example",
+			wantDisplay:   "jane@bücher.example",
+			wantCanonical: "jane@xn--bcher-kva.example",
+		},
+		{
+			name:          "punycode domains are displayed in unicode",
+			in:            "jane@xn--bcher-kva.example",
+			wantDisplay:   "jane@bücher.example",
+			wantCanonical: "jane@xn--bcher-kva.example",
+		},
+		{name: "empty", in: "", wantErr: true},
+		{name: "no at sign", in: "jane.example.com", wantErr: true},
+		{name: "no local part", in: "@example.com", wantErr: true},
+		{name: "no domain", in: "jane@", wantErr: true},
+		{name: "invalid domain", in: "jane@exa mple.com", wantErr: true},
+		{name: "gmail address that is only a tag", providerRules: true, in: "+news@gmail.com", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := NewNormalizer(configuration.Config{EmailProviderRules: tt.providerRules})
+			got, err := n.Normalize(tt.in)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidFormat) {
+					t.Errorf("Normalize(%q) error = %v, want %v", tt.in, err, ErrInvalidFormat)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Normalize(%q) error = %v", tt.in, err)
+			}
+			if got.Display != tt.wantDisplay {
+				t.Errorf("Display = %q, want %q", got.Display, tt.wantDisplay)
+			}
+			if got.Canonical != tt.wantCanonical {
+				t.Errorf("Canonical = %q, want %q", got.Canonical, tt.wantCanonical)
+			}
+		})
+	}
+}
+
+func TestNormalizeDomain(t *testing.T) {
+	tests := []struct {
+		providerRules bool
+		in            string
+		want          string
+	}{
+		{false, "Example.COM", "example.com"},
+		{false, "googlemail.com", "googlemail.com"},
+		{true, "googlemail.com", "gmail.com"},
+		{false, "bücher.example", "xn--bcher-kva.example"},
+	}
+
+	for _, tt := range tests {
+		n := NewNormalizer(configuration.Config{EmailProviderRules: tt.providerRules})
+		got, err := n.NormalizeDomain(tt.in)
+		if err != nil {
+			t.Fatalf("NormalizeDomain(%q) error = %v", tt.in, err)
+		}
+		if got != tt.want {
+			t.Errorf("NormalizeDomain(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRulesChangeWithProviderRules(t *testing.T) {
+	off := NewNormalizer(configuration.Config{})
+	on := NewNormalizer(configuration.Config{EmailProviderRules: true})
+	if off.Rules() == on.Rules() {
+		t.Errorf("Rules() = %q with and without provider rules, want them to differ", off.Rules())
+	}
+}
@@ -7,5 +7,12 @@ import (
 )
 
 func (s Server) CreateAccount(ctx context.Context, request *accountV1.CreateAccountRequest) (*accountV1.CreateAccountResponse, error) {
-	return s.service.CreateAccount(ctx, request)
+	response, err := s.service.CreateAccount(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	return &accountV1.CreateAccountResponse{
+		Account: response.Account.ToProtobuf(),
+	}, nil
 }
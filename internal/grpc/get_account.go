@@ -7,5 +7,12 @@ import (
 )
 
 func (s Server) GetAccount(ctx context.Context, request *accountV1.GetAccountRequest) (*accountV1.GetAccountResponse, error) {
-	return s.service.GetAccount(ctx, request)
+	response, err := s.service.GetAccount(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	return &accountV1.GetAccountResponse{
+		Account: response.Account.ToProtobuf(),
+	}, nil
 }
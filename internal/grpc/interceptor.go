@@ -0,0 +1,43 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/AlpacaLabs/api-account/internal/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const metadataKeyForAuthorization = "authorization"
+
+// authenticate verifies the request's bearer token, if one was sent, and
+// places the resulting principal on the context. Requests without a token
+// are passed through anonymously; it's up to the service to decide whether
+// that's acceptable.
+func authenticate(authenticator auth.Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, ok := metadata.FromIncomingContext(ctx)
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		values := md.Get(metadataKeyForAuthorization)
+		if len(values) == 0 {
+			return handler(ctx, req)
+		}
+
+		token, err := auth.ParseBearerToken(values[0])
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+
+		principal, err := authenticator.Authenticate(ctx, token)
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+
+		return handler(auth.NewContext(ctx, principal), req)
+	}
+}
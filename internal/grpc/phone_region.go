@@ -0,0 +1,29 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/AlpacaLabs/api-account/internal/phonenum"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const metadataKeyForPhoneRegion = "phone-region"
+
+// phoneRegion lets callers override the default region that phone
+// numbers without a country code are read as belonging to.
+func phoneRegion() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, ok := metadata.FromIncomingContext(ctx)
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		values := md.Get(metadataKeyForPhoneRegion)
+		if len(values) == 0 {
+			return handler(ctx, req)
+		}
+
+		return handler(phonenum.NewRegionContext(ctx, values[0]), req)
+	}
+}
@@ -0,0 +1,93 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+
+	"github.com/AlpacaLabs/api-account/internal/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const accountServicePrefix = "/alpacalabs.account.v1.AccountService/"
+
+// policies maps each RPC's full method name to the permissions a caller needs.
+// Methods outside the account service (health checks, reflection) are not
+// listed and are always allowed. Account service methods that are missing
+// from this map are denied.
+var policies = map[string]auth.Policy{
+	accountServicePrefix + "CreateAccount": {
+		Public: true,
+	},
+	accountServicePrefix + "GetAccount": {
+		AnyRole: []auth.Role{auth.RoleSelf, auth.RoleSupport, auth.RoleAdmin},
+		Scopes:  []string{auth.ScopeAccountsRead},
+	},
+	// GetAccounts and BatchGetAccounts have no RPCs in the v1 protobuf yet,
+	// so they're only served over HTTP. They should get the same policy as
+	// their HTTP routes: support and admins, with the read scope.
+
+	accountServicePrefix + "RegisterEmailAddress": {
+		AnyRole: []auth.Role{auth.RoleSelf, auth.RoleAdmin},
+		Scopes:  []string{auth.ScopeAccountsWrite},
+	},
+	accountServicePrefix + "UnregisterEmailAddress": {
+		AnyRole: []auth.Role{auth.RoleSelf, auth.RoleAdmin},
+		Scopes:  []string{auth.ScopeAccountsWrite},
+	},
+	accountServicePrefix + "GetEmailAddress": {
+		AnyRole: []auth.Role{auth.RoleSelf, auth.RoleSupport, auth.RoleAdmin},
+		Scopes:  []string{auth.ScopeAccountsRead},
+	},
+	accountServicePrefix + "GetEmailAddresses": {
+		AnyRole: []auth.Role{auth.RoleSupport, auth.RoleAdmin},
+		Scopes:  []string{auth.ScopeAccountsRead},
+	},
+
+	accountServicePrefix + "RegisterPhoneNumber": {
+		AnyRole: []auth.Role{auth.RoleSelf, auth.RoleAdmin},
+		Scopes:  []string{auth.ScopeAccountsWrite},
+	},
+	accountServicePrefix + "UnregisterPhoneNumber": {
+		AnyRole: []auth.Role{auth.RoleSelf, auth.RoleAdmin},
+		Scopes:  []string{auth.ScopeAccountsWrite},
+	},
+	accountServicePrefix + "GetPhoneNumber": {
+		AnyRole: []auth.Role{auth.RoleSelf, auth.RoleSupport, auth.RoleAdmin},
+		Scopes:  []string{auth.ScopeAccountsRead},
+	},
+	accountServicePrefix + "GetPhoneNumbers": {
+		AnyRole: []auth.Role{auth.RoleSupport, auth.RoleAdmin},
+		Scopes:  []string{auth.ScopeAccountsRead},
+	},
+}
+
+// authorize enforces the policy registered for each RPC.
+// It must run after authenticate.
+func authorize() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		policy, ok := policies[info.FullMethod]
+		if !ok {
+			if strings.HasPrefix(info.FullMethod, accountServicePrefix) {
+				return nil, status.Errorf(codes.PermissionDenied, "no authorization policy for %s", info.FullMethod)
+			}
+			return handler(ctx, req)
+		}
+
+		if policy.Public {
+			return handler(ctx, req)
+		}
+
+		principal, ok := auth.FromContext(ctx)
+		if !ok {
+			return nil, status.Error(codes.Unauthenticated, "request must carry a valid bearer token")
+		}
+
+		if err := policy.Authorize(principal); err != nil {
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		}
+
+		return handler(ctx, req)
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 	health "google.golang.org/grpc/health/grpc_health_v1"
 
+	"github.com/AlpacaLabs/api-account/internal/auth"
 	"github.com/AlpacaLabs/api-account/internal/configuration"
 	"github.com/AlpacaLabs/api-account/internal/service"
 	accountV1 "github.com/AlpacaLabs/protorepo-account-go/alpacalabs/account/v1"
@@ -15,14 +16,16 @@ import (
 )
 
 type Server struct {
-	config  configuration.Config
-	service service.Service
+	config        configuration.Config
+	service       service.Service
+	authenticator auth.Authenticator
 }
 
-func NewServer(config configuration.Config, service service.Service) Server {
+func NewServer(config configuration.Config, service service.Service, authenticator auth.Authenticator) Server {
 	return Server{
-		config:  config,
-		service: service,
+		config:        config,
+		service:       service,
+		authenticator: authenticator,
 	}
 }
 
@@ -36,7 +39,13 @@ func (s Server) Run() {
 		log.Fatalf("Failed to listen: %v", err)
 	}
 
-	grpcServer := grpc.NewServer()
+	grpcServer := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			authenticate(s.authenticator),
+			authorize(),
+			phoneRegion(),
+		),
+	)
 
 	health.RegisterHealthServer(grpcServer, s)
 	accountV1.RegisterAccountServiceServer(grpcServer, s)
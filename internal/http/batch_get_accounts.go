@@ -0,0 +1,25 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/AlpacaLabs/api-account/internal/service"
+)
+
+// BatchGetAccounts is served over HTTP only, since the v1 protobuf
+// has no BatchGetAccounts RPC.
+func (s Server) BatchGetAccounts(w http.ResponseWriter, r *http.Request) {
+	request := &service.BatchGetAccountsRequest{}
+	if err := readJSON(r, request); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	response, err := s.service.BatchGetAccounts(r.Context(), request)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,23 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/AlpacaLabs/api-account/internal/service"
+)
+
+func (s Server) ConfirmEmailAddressWithToken(w http.ResponseWriter, r *http.Request) {
+	request := &service.ConfirmEmailAddressWithTokenRequest{}
+	if err := readJSON(r, request); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	response, err := s.service.ConfirmEmailAddressWithToken(r.Context(), request)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
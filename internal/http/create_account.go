@@ -0,0 +1,23 @@
+package http
+
+import (
+	"net/http"
+
+	accountV1 "github.com/AlpacaLabs/protorepo-account-go/alpacalabs/account/v1"
+)
+
+func (s Server) CreateAccount(w http.ResponseWriter, r *http.Request) {
+	request := &accountV1.CreateAccountRequest{}
+	if err := readJSON(r, request); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	response, err := s.service.CreateAccount(r.Context(), request)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, response)
+}
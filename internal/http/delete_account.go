@@ -0,0 +1,32 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/AlpacaLabs/api-account/internal/service"
+	"github.com/gorilla/mux"
+)
+
+func (s Server) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	response, err := s.service.DeleteAccount(r.Context(), &service.DeleteAccountRequest{
+		AccountID: mux.Vars(r)["id"],
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s Server) RestoreAccount(w http.ResponseWriter, r *http.Request) {
+	response, err := s.service.RestoreAccount(r.Context(), &service.RestoreAccountRequest{
+		AccountID: mux.Vars(r)["id"],
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
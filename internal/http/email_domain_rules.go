@@ -0,0 +1,49 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/AlpacaLabs/api-account/internal/service"
+	"github.com/gorilla/mux"
+)
+
+func (s Server) GetEmailDomainRules(w http.ResponseWriter, r *http.Request) {
+	response, err := s.service.GetEmailDomainRules(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s Server) PutEmailDomainRule(w http.ResponseWriter, r *http.Request) {
+	request := &service.PutEmailDomainRuleRequest{}
+	if err := readJSON(r, request); err != nil {
+		writeError(w, err)
+		return
+	}
+	request.Domain = mux.Vars(r)["domain"]
+
+	response, err := s.service.PutEmailDomainRule(r.Context(), request)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s Server) DeleteEmailDomainRule(w http.ResponseWriter, r *http.Request) {
+	request := &service.DeleteEmailDomainRuleRequest{
+		Domain: mux.Vars(r)["domain"],
+	}
+
+	response, err := s.service.DeleteEmailDomainRule(r.Context(), request)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,24 @@
+package http
+
+import (
+	"net/http"
+
+	accountV1 "github.com/AlpacaLabs/protorepo-account-go/alpacalabs/account/v1"
+	"github.com/gorilla/mux"
+)
+
+func (s Server) GetAccount(w http.ResponseWriter, r *http.Request) {
+	request := &accountV1.GetAccountRequest{
+		AccountIdentifier: &accountV1.GetAccountRequest_AccountId{
+			AccountId: mux.Vars(r)["id"],
+		},
+	}
+
+	response, err := s.service.GetAccount(r.Context(), request)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
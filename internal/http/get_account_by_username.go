@@ -0,0 +1,26 @@
+package http
+
+import (
+	"net/http"
+
+	accountV1 "github.com/AlpacaLabs/protorepo-account-go/alpacalabs/account/v1"
+	"github.com/gorilla/mux"
+)
+
+// GetAccountByUsername looks up an account by its username, or by one it
+// recently changed away from, in which case the response says it was renamed.
+func (s Server) GetAccountByUsername(w http.ResponseWriter, r *http.Request) {
+	request := &accountV1.GetAccountRequest{
+		AccountIdentifier: &accountV1.GetAccountRequest_Username{
+			Username: mux.Vars(r)["username"],
+		},
+	}
+
+	response, err := s.service.GetAccount(r.Context(), request)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,35 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/AlpacaLabs/api-account/internal/db"
+	"github.com/AlpacaLabs/api-account/internal/service"
+)
+
+// GetAccounts is served over HTTP only, since the v1 protobuf
+// has no GetAccounts RPC.
+func (s Server) GetAccounts(w http.ResponseWriter, r *http.Request) {
+	q := newQuery(r.URL.Query())
+	request := &service.GetAccountsRequest{
+		Filter: db.AccountFilter{
+			CreatedAfter:   q.time("created_after"),
+			CreatedBefore:  q.time("created_before"),
+			IncludeDeleted: q.bool("include_deleted"),
+		},
+		CursorRequest:         q.cursorRequest(),
+		IncludeContactDetails: q.bool("include_contact_details"),
+	}
+	if q.err != nil {
+		writeError(w, q.err)
+		return
+	}
+
+	response, err := s.service.GetAccounts(r.Context(), request)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
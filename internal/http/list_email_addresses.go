@@ -0,0 +1,36 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/AlpacaLabs/api-account/internal/db"
+	"github.com/AlpacaLabs/api-account/internal/service"
+)
+
+func (s Server) ListEmailAddresses(w http.ResponseWriter, r *http.Request) {
+	q := newQuery(r.URL.Query())
+	request := &service.ListEmailAddressesRequest{
+		Filter: db.EmailAddressFilter{
+			Confirmed:      q.optionalBool("confirmed"),
+			PrimaryOnly:    q.bool("primary_only"),
+			CreatedAfter:   q.time("created_after"),
+			CreatedBefore:  q.time("created_before"),
+			IncludeDeleted: q.bool("include_deleted"),
+			Domain:         q.string("domain"),
+			AccountID:      q.string("account_id"),
+		},
+		CursorRequest: q.cursorRequest(),
+	}
+	if q.err != nil {
+		writeError(w, q.err)
+		return
+	}
+
+	response, err := s.service.ListEmailAddresses(r.Context(), request)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,35 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/AlpacaLabs/api-account/internal/db"
+	"github.com/AlpacaLabs/api-account/internal/service"
+)
+
+func (s Server) ListPhoneNumbers(w http.ResponseWriter, r *http.Request) {
+	q := newQuery(r.URL.Query())
+	request := &service.ListPhoneNumbersRequest{
+		Filter: db.PhoneNumberFilter{
+			Confirmed:      q.optionalBool("confirmed"),
+			CreatedAfter:   q.time("created_after"),
+			CreatedBefore:  q.time("created_before"),
+			IncludeDeleted: q.bool("include_deleted"),
+			CountryCode:    q.int("country_code"),
+			AccountID:      q.string("account_id"),
+		},
+		CursorRequest: q.cursorRequest(),
+	}
+	if q.err != nil {
+		writeError(w, q.err)
+		return
+	}
+
+	response, err := s.service.ListPhoneNumbers(r.Context(), request)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
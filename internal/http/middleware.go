@@ -0,0 +1,80 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/AlpacaLabs/api-account/internal/auth"
+	"github.com/AlpacaLabs/api-account/internal/phonenum"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	headerForAuthorization = "Authorization"
+	headerForPhoneRegion   = "Phone-Region"
+)
+
+// authenticate verifies the request's bearer token, if one was sent, and
+// places the resulting principal on the request context. Requests without
+// a token are passed through anonymously.
+func (s Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get(headerForAuthorization)
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token, err := auth.ParseBearerToken(header)
+		if err != nil {
+			writeError(w, status.Error(codes.Unauthenticated, err.Error()))
+			return
+		}
+
+		principal, err := s.authenticator.Authenticate(r.Context(), token)
+		if err != nil {
+			writeError(w, status.Error(codes.Unauthenticated, err.Error()))
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), principal)))
+	})
+}
+
+// phoneRegion lets callers override the default region that phone
+// numbers without a country code are read as belonging to.
+func (s Server) phoneRegion(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		region := r.Header.Get(headerForPhoneRegion)
+		if region == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(phonenum.NewRegionContext(r.Context(), region)))
+	})
+}
+
+// authorize wraps a handler so that it's only invoked for callers
+// satisfying the policy. It must run after authenticate.
+func (s Server) authorize(policy auth.Policy, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if policy.Public {
+			next(w, r)
+			return
+		}
+
+		principal, ok := auth.FromContext(r.Context())
+		if !ok {
+			writeError(w, status.Error(codes.Unauthenticated, "request must carry a valid bearer token"))
+			return
+		}
+
+		if err := policy.Authorize(principal); err != nil {
+			writeError(w, status.Error(codes.PermissionDenied, err.Error()))
+			return
+		}
+
+		next(w, r)
+	}
+}
@@ -0,0 +1,109 @@
+package http
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	paginationV1 "github.com/AlpacaLabs/protorepo-pagination-go/alpacalabs/pagination/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// query reads typed values out of a URL query string,
+// remembering the first malformed one.
+type query struct {
+	values url.Values
+	err    error
+}
+
+func newQuery(values url.Values) *query {
+	return &query{values: values}
+}
+
+func (q *query) fail(key string, err error) {
+	if q.err == nil {
+		q.err = status.Errorf(codes.InvalidArgument, "invalid %s: %v", key, err)
+	}
+}
+
+func (q *query) string(key string) string {
+	return q.values.Get(key)
+}
+
+func (q *query) bool(key string) bool {
+	b := q.optionalBool(key)
+	return b != nil && *b
+}
+
+func (q *query) optionalBool(key string) *bool {
+	v := q.values.Get(key)
+	if v == "" {
+		return nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		q.fail(key, err)
+		return nil
+	}
+	return &b
+}
+
+func (q *query) int(key string) int {
+	v := q.values.Get(key)
+	if v == "" {
+		return 0
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		q.fail(key, err)
+		return 0
+	}
+	return i
+}
+
+// time parses an RFC 3339 timestamp.
+func (q *query) time(key string) *time.Time {
+	v := q.values.Get(key)
+	if v == "" {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		q.fail(key, err)
+		return nil
+	}
+	return &t
+}
+
+// cursorRequest reads the cursor, count and sort parameters.
+// Sort clauses are comma-separated field names, each optionally
+// followed by ":asc" or ":desc", e.g. "created_at:desc,email_address".
+func (q *query) cursorRequest() *paginationV1.CursorRequest {
+	out := &paginationV1.CursorRequest{
+		Cursor: q.string("cursor"),
+		Count:  int32(q.int("count")),
+	}
+
+	sort := q.string("sort")
+	if sort == "" {
+		return out
+	}
+	for _, clause := range strings.Split(sort, ",") {
+		parts := strings.SplitN(strings.TrimSpace(clause), ":", 2)
+		c := &paginationV1.SortClause{FieldName: parts[0], Sort: paginationV1.Sort_SORT_ASC}
+		if len(parts) == 2 {
+			switch strings.ToLower(parts[1]) {
+			case "asc":
+			case "desc":
+				c.Sort = paginationV1.Sort_SORT_DESC
+			default:
+				q.fail("sort", fmt.Errorf("unknown direction %q", parts[1]))
+			}
+		}
+		out.SortClauses = append(out.SortClauses, c)
+	}
+	return out
+}
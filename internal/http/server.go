@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/AlpacaLabs/api-account/internal/auth"
 	"github.com/AlpacaLabs/api-account/internal/configuration"
 	"github.com/AlpacaLabs/api-account/internal/service"
 	"github.com/gorilla/mux"
@@ -11,19 +12,91 @@ import (
 )
 
 type Server struct {
-	config  configuration.Config
-	service service.Service
+	config        configuration.Config
+	service       service.Service
+	authenticator auth.Authenticator
 }
 
-func NewServer(config configuration.Config, service service.Service) Server {
+func NewServer(config configuration.Config, service service.Service, authenticator auth.Authenticator) Server {
 	return Server{
-		config:  config,
-		service: service,
+		config:        config,
+		service:       service,
+		authenticator: authenticator,
 	}
 }
 
 func (s Server) Run() {
 	r := mux.NewRouter()
+	r.Use(s.authenticate)
+	r.Use(s.phoneRegion)
+
+	r.HandleFunc("/accounts", s.authorize(auth.Policy{
+		Public: true,
+	}, s.CreateAccount)).Methods(http.MethodPost)
+	r.HandleFunc("/accounts", s.authorize(auth.Policy{
+		AnyRole: []auth.Role{auth.RoleSupport, auth.RoleAdmin},
+		Scopes:  []string{auth.ScopeAccountsRead},
+	}, s.GetAccounts)).Methods(http.MethodGet)
+	r.HandleFunc("/accounts/batch-get", s.authorize(auth.Policy{
+		AnyRole: []auth.Role{auth.RoleSupport, auth.RoleAdmin},
+		Scopes:  []string{auth.ScopeAccountsRead},
+	}, s.BatchGetAccounts)).Methods(http.MethodPost)
+	r.HandleFunc("/accounts/{id}", s.authorize(auth.Policy{
+		AnyRole: []auth.Role{auth.RoleSelf, auth.RoleSupport, auth.RoleAdmin},
+		Scopes:  []string{auth.ScopeAccountsRead},
+	}, s.GetAccount)).Methods(http.MethodGet)
+	r.HandleFunc("/accounts/{id}", s.authorize(auth.Policy{
+		AnyRole: []auth.Role{auth.RoleSelf, auth.RoleAdmin},
+		Scopes:  []string{auth.ScopeAccountsWrite},
+	}, s.UpdateAccount)).Methods(http.MethodPatch)
+	r.HandleFunc("/accounts/{id}", s.authorize(auth.Policy{
+		AnyRole: []auth.Role{auth.RoleSelf, auth.RoleAdmin},
+		Scopes:  []string{auth.ScopeAccountsWrite},
+	}, s.DeleteAccount)).Methods(http.MethodDelete)
+	r.HandleFunc("/accounts/{id}/restore", s.authorize(auth.Policy{
+		AnyRole: []auth.Role{auth.RoleSelf, auth.RoleAdmin},
+		Scopes:  []string{auth.ScopeAccountsWrite},
+	}, s.RestoreAccount)).Methods(http.MethodPost)
+	r.HandleFunc("/accounts/{id}/primary-email-address", s.authorize(auth.Policy{
+		AnyRole: []auth.Role{auth.RoleSelf, auth.RoleAdmin},
+		Scopes:  []string{auth.ScopeAccountsWrite},
+	}, s.SetPrimaryEmailAddress)).Methods(http.MethodPut)
+	r.HandleFunc("/usernames/{username}/account", s.authorize(auth.Policy{
+		AnyRole: []auth.Role{auth.RoleSelf, auth.RoleSupport, auth.RoleAdmin},
+		Scopes:  []string{auth.ScopeAccountsRead},
+	}, s.GetAccountByUsername)).Methods(http.MethodGet)
+
+	r.HandleFunc("/email-addresses", s.authorize(auth.Policy{
+		AnyRole: []auth.Role{auth.RoleSupport, auth.RoleAdmin},
+		Scopes:  []string{auth.ScopeAccountsRead},
+	}, s.ListEmailAddresses)).Methods(http.MethodGet)
+
+	// The token itself proves the caller received the confirmation email.
+	r.HandleFunc("/email-addresses/confirm", s.authorize(auth.Policy{
+		Public: true,
+	}, s.ConfirmEmailAddressWithToken)).Methods(http.MethodPost)
+
+	r.HandleFunc("/email-domain-rules", s.authorize(auth.Policy{
+		AnyRole: []auth.Role{auth.RoleAdmin},
+		Scopes:  []string{auth.ScopeAccountsRead},
+	}, s.GetEmailDomainRules)).Methods(http.MethodGet)
+	r.HandleFunc("/email-domain-rules/{domain}", s.authorize(auth.Policy{
+		AnyRole: []auth.Role{auth.RoleAdmin},
+		Scopes:  []string{auth.ScopeAccountsWrite},
+	}, s.PutEmailDomainRule)).Methods(http.MethodPut)
+	r.HandleFunc("/email-domain-rules/{domain}", s.authorize(auth.Policy{
+		AnyRole: []auth.Role{auth.RoleAdmin},
+		Scopes:  []string{auth.ScopeAccountsWrite},
+	}, s.DeleteEmailDomainRule)).Methods(http.MethodDelete)
+
+	r.HandleFunc("/phone-numbers", s.authorize(auth.Policy{
+		AnyRole: []auth.Role{auth.RoleSupport, auth.RoleAdmin},
+		Scopes:  []string{auth.ScopeAccountsRead},
+	}, s.ListPhoneNumbers)).Methods(http.MethodGet)
+	r.HandleFunc("/phone-numbers/{id}/verify", s.authorize(auth.Policy{
+		AnyRole: []auth.Role{auth.RoleSelf, auth.RoleAdmin},
+		Scopes:  []string{auth.ScopeAccountsWrite},
+	}, s.VerifyPhoneNumber)).Methods(http.MethodPost)
 
 	addr := fmt.Sprintf(":%d", s.config.HTTPPort)
 	log.Infof("Listening for HTTP on %s...\n", addr)
@@ -0,0 +1,25 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/AlpacaLabs/api-account/internal/service"
+	"github.com/gorilla/mux"
+)
+
+func (s Server) SetPrimaryEmailAddress(w http.ResponseWriter, r *http.Request) {
+	request := &service.SetPrimaryEmailAddressRequest{}
+	if err := readJSON(r, request); err != nil {
+		writeError(w, err)
+		return
+	}
+	request.AccountID = mux.Vars(r)["id"]
+
+	response, err := s.service.SetPrimaryEmailAddress(r.Context(), request)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
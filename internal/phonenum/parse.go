@@ -0,0 +1,126 @@
+package phonenum
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/AlpacaLabs/api-account/internal/configuration"
+	"github.com/ttacon/libphonenumber"
+)
+
+var ErrInvalidNumber = errors.New("invalid phone number")
+
+// Number types, as stored alongside phone numbers.
+const (
+	TypeLandline         = "landline"
+	TypeMobile           = "mobile"
+	TypeLandlineOrMobile = "landline_or_mobile"
+	TypeTollFree         = "toll_free"
+	TypePremiumRate      = "premium_rate"
+	TypeSharedCost       = "shared_cost"
+	TypeVoIP             = "voip"
+	TypePersonalNumber   = "personal_number"
+	TypePager            = "pager"
+	TypeUAN              = "uan"
+	TypeVoicemail        = "voicemail"
+	TypeUnknown          = "unknown"
+)
+
+var numberTypes = map[libphonenumber.PhoneNumberType]string{
+	libphonenumber.FIXED_LINE:           TypeLandline,
+	libphonenumber.MOBILE:               TypeMobile,
+	libphonenumber.FIXED_LINE_OR_MOBILE: TypeLandlineOrMobile,
+	libphonenumber.TOLL_FREE:            TypeTollFree,
+	libphonenumber.PREMIUM_RATE:         TypePremiumRate,
+	libphonenumber.SHARED_COST:          TypeSharedCost,
+	libphonenumber.VOIP:                 TypeVoIP,
+	libphonenumber.PERSONAL_NUMBER:      TypePersonalNumber,
+	libphonenumber.PAGER:                TypePager,
+	libphonenumber.UAN:                  TypeUAN,
+	libphonenumber.VOICEMAIL:            TypeVoicemail,
+	libphonenumber.UNKNOWN:              TypeUnknown,
+}
+
+// Number is a validated phone number.
+type Number struct {
+	// E164 is the number in E.164 format, e.g. "+15551234567".
+	// It's the form phone numbers are stored and looked up in.
+	E164 string
+
+	// CountryCode is the country calling code, e.g. 1 or 44.
+	CountryCode int
+
+	// Type is what kind of line the number belongs to, e.g. "mobile".
+	Type string
+}
+
+// CanReceiveSMS reports whether text messages can be sent to the number.
+// Only numbers known to be landlines are ruled out.
+func (n Number) CanReceiveSMS() bool {
+	return n.Type != TypeLandline
+}
+
+// Parser validates phone numbers. Numbers written without a leading
+// + and country code are read as belonging to a default region.
+type Parser struct {
+	defaultRegion string
+}
+
+func NewParser(config configuration.Config) (Parser, error) {
+	region, err := checkRegion(config.DefaultPhoneRegion)
+	if err != nil {
+		return Parser{}, err
+	}
+	return Parser{defaultRegion: region}, nil
+}
+
+// Parse validates a phone number. The region the context carries, if any,
+// overrides the default one.
+func (p Parser) Parse(ctx context.Context, phoneNumber string) (Number, error) {
+	region := p.defaultRegion
+	if r, ok := RegionFromContext(ctx); ok {
+		var err error
+		if region, err = checkRegion(r); err != nil {
+			return Number{}, err
+		}
+	}
+
+	num, err := libphonenumber.Parse(phoneNumber, region)
+	if err != nil {
+		return Number{}, fmt.Errorf("%w: %v", ErrInvalidNumber, err)
+	}
+	if !libphonenumber.IsValidNumber(num) {
+		return Number{}, ErrInvalidNumber
+	}
+
+	return Number{
+		E164:        libphonenumber.Format(num, libphonenumber.E164),
+		CountryCode: int(num.GetCountryCode()),
+		Type:        numberTypes[libphonenumber.GetNumberType(num)],
+	}, nil
+}
+
+// checkRegion makes sure the region is a supported two-letter region code, like "US".
+func checkRegion(region string) (string, error) {
+	region = strings.ToUpper(strings.TrimSpace(region))
+	if _, ok := libphonenumber.GetSupportedRegions()[region]; !ok {
+		return "", fmt.Errorf("unsupported phone number region: %q", region)
+	}
+	return region, nil
+}
+
+type regionKey struct{}
+
+// NewRegionContext returns a context carrying the region that phone numbers
+// in the request should be read as belonging to.
+func NewRegionContext(ctx context.Context, region string) context.Context {
+	return context.WithValue(ctx, regionKey{}, region)
+}
+
+// RegionFromContext returns the region carried by the context, if any.
+func RegionFromContext(ctx context.Context) (string, bool) {
+	region, ok := ctx.Value(regionKey{}).(string)
+	return region, ok && region != ""
+}
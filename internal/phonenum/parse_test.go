@@ -0,0 +1,98 @@
+package phonenum
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/AlpacaLabs/api-account/internal/configuration"
+)
+
+func TestParse(t *testing.T) {
+	p, err := NewParser(configuration.Config{DefaultPhoneRegion: "US"})
+	if err != nil {
+		t.Fatalf("NewParser() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		region     string
+		in         string
+		want       Number
+		wantErr    bool
+		canReceive bool
+	}{
+		{
+			name:       "national number in the default region",
+			in:         "(201) 555-0123",
+			want:       Number{E164: "+12015550123", CountryCode: 1, Type: TypeLandlineOrMobile},
+			canReceive: true,
+		},
+		{
+			name:       "international number ignores the default region",
+			in:         "+44 7400 123456",
+			want:       Number{E164: "+447400123456", CountryCode: 44, Type: TypeMobile},
+			canReceive: true,
+		},
+		{
+			name:       "national number in the context's region",
+			region:     "gb",
+			in:         "07400 123456",
+			want:       Number{E164: "+447400123456", CountryCode: 44, Type: TypeMobile},
+			canReceive: true,
+		},
+		{
+			name:   "landline",
+			region: "GB",
+			in:     "0121 234 5678",
+			want:   Number{E164: "+441212345678", CountryCode: 44, Type: TypeLandline},
+		},
+		{
+			name:       "toll free",
+			in:         "800-234-5678",
+			want:       Number{E164: "+18002345678", CountryCode: 1, Type: TypeTollFree},
+			canReceive: true,
+		},
+		{name: "empty", in: "", wantErr: true},
+		{name: "letters", in: "not a number", wantErr: true},
+		{name: "too short", in: "123", wantErr: true},
+		{name: "not a valid number in the region", in: "+1 555 0000", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := NewRegionContext(context.Background(), tt.region)
+			got, err := p.Parse(ctx, tt.in)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidNumber) {
+					t.Errorf("Parse(%q) error = %v, want %v", tt.in, err, ErrInvalidNumber)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.in, err)
+			}
+			if got != tt.want {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+			if got.CanReceiveSMS() != tt.canReceive {
+				t.Errorf("CanReceiveSMS() = %v, want %v", got.CanReceiveSMS(), tt.canReceive)
+			}
+		})
+	}
+}
+
+func TestParseRejectsUnsupportedRegions(t *testing.T) {
+	if _, err := NewParser(configuration.Config{DefaultPhoneRegion: "XX"}); err == nil {
+		t.Error("NewParser() with region XX error = nil, want an error")
+	}
+
+	p, err := NewParser(configuration.Config{DefaultPhoneRegion: "US"})
+	if err != nil {
+		t.Fatalf("NewParser() error = %v", err)
+	}
+	ctx := NewRegionContext(context.Background(), "XX")
+	if _, err := p.Parse(ctx, "2015550123"); err == nil {
+		t.Error("Parse() with context region XX error = nil, want an error")
+	}
+}
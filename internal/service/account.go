@@ -2,8 +2,205 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"time"
+
+	"github.com/AlpacaLabs/api-account/internal/auth"
+	"github.com/AlpacaLabs/api-account/internal/db"
+	"github.com/AlpacaLabs/api-account/internal/db/entities"
+	"github.com/AlpacaLabs/api-account/internal/username"
+	accountV1 "github.com/AlpacaLabs/protorepo-account-go/alpacalabs/account/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	FieldUsername              = "username"
+	FieldPrimaryEmailAddressID = "primary_email_address_id"
 )
 
-func (s Service) UpdateAccount(ctx context.Context) {}
+// AccountDetails is the full representation of an account, including
+// the fields that the v1 Account protobuf does not carry.
+type AccountDetails struct {
+	ID                    string    `json:"id"`
+	Username              string    `json:"username,omitempty"`
+	PrimaryEmailAddressID string    `json:"primary_email_address_id,omitempty"`
+	CreatedAt             time.Time `json:"created_at"`
+	LastModifiedAt        time.Time `json:"last_modified_at"`
+
+	// EmailAddresses and PhoneNumbers are only populated by
+	// operations that return the account's contact details.
+	EmailAddresses []*accountV1.EmailAddress `json:"email_addresses,omitempty"`
+	PhoneNumbers   []*accountV1.PhoneNumber  `json:"phone_numbers,omitempty"`
+}
+
+// ToProtobuf returns the parts of the account the v1 protobuf can carry.
+func (a AccountDetails) ToProtobuf() *accountV1.Account {
+	return &accountV1.Account{
+		Id:             a.ID,
+		EmailAddresses: a.EmailAddresses,
+		PhoneNumbers:   a.PhoneNumbers,
+	}
+}
+
+func newAccountDetails(a entities.Account) AccountDetails {
+	return AccountDetails{
+		ID:                    a.ID,
+		Username:              a.Username.String,
+		PrimaryEmailAddressID: a.PrimaryEmailAddressID.String,
+		CreatedAt:             a.CreatedAt,
+		LastModifiedAt:        a.LastModifiedAt,
+	}
+}
+
+// loadContactDetails fills in the email addresses and phone numbers of
+// the given accounts with one query each, masking them as needed.
+func loadContactDetails(ctx context.Context, tx db.Transaction, principal auth.Principal, accounts []AccountDetails) error {
+	if len(accounts) == 0 {
+		return nil
+	}
+
+	accountIDs := make([]string, len(accounts))
+	for i, a := range accounts {
+		accountIDs[i] = a.ID
+	}
+
+	emailAddresses, err := tx.GetEmailAddressesForAccounts(ctx, accountIDs)
+	if err != nil {
+		return err
+	}
+	phoneNumbers, err := tx.GetPhoneNumbersForAccounts(ctx, accountIDs)
+	if err != nil {
+		return err
+	}
+
+	for i := range accounts {
+		a := &accounts[i]
+		a.EmailAddresses = emailAddresses[a.ID]
+		a.PhoneNumbers = phoneNumbers[a.ID]
+
+		if mustMask(principal, a.ID) {
+			for _, e := range a.EmailAddresses {
+				maskEmailAddress(e)
+			}
+			for _, p := range a.PhoneNumbers {
+				maskPhoneNumber(p)
+			}
+		}
+	}
+
+	return nil
+}
+
+type UpdateAccountRequest struct {
+	AccountID string `json:"account_id"`
+
+	// UpdateMask names the fields to overwrite. Fields not named in
+	// the mask are left untouched, even if they're set on the request.
+	UpdateMask []string `json:"update_mask"`
+
+	Username              string `json:"username"`
+	PrimaryEmailAddressID string `json:"primary_email_address_id"`
+
+	// LastModifiedAt is the version of the account the caller last read.
+	// The update is rejected if the account has been modified since.
+	LastModifiedAt time.Time `json:"last_modified_at"`
+}
+
+type UpdateAccountResponse struct {
+	Account AccountDetails `json:"account"`
+}
+
+// UpdateAccount overwrites the fields named in the request's update mask.
+// It uses optimistic concurrency control: callers must send back the
+// last_modified_at they read, so two writers can't silently clobber
+// each other's changes.
+func (s Service) UpdateAccount(ctx context.Context, request *UpdateAccountRequest) (*UpdateAccountResponse, error) {
+	principal, err := getPrincipal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !canWrite(principal, request.AccountID) {
+		return nil, ErrUnowned
+	}
+
+	if request.LastModifiedAt.IsZero() {
+		return nil, ErrMissingLastModifiedAt
+	}
+
+	var updateUsername, updatePrimaryEmailAddress bool
+	for _, field := range request.UpdateMask {
+		switch field {
+		case FieldUsername:
+			updateUsername = true
+		case FieldPrimaryEmailAddressID:
+			updatePrimaryEmailAddress = true
+		default:
+			return nil, status.Errorf(codes.InvalidArgument, "unknown field in update mask: %s", field)
+		}
+	}
+
+	var name username.Username
+	if updateUsername {
+		if name, err = s.usernames.Check(request.Username); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
+	out := &UpdateAccountResponse{}
+
+	err = s.dbClient.RunInTransaction(ctx, func(ctx context.Context, tx db.Transaction) error {
+		account, err := tx.GetAccountByID(ctx, request.AccountID)
+		if err != nil {
+			return err
+		}
+
+		// Fail fast; the UPDATE below re-checks this to guard against races.
+		if !account.LastModifiedAt.Equal(request.LastModifiedAt) {
+			return ErrStaleAccount
+		}
+
+		lastModifiedAt := account.LastModifiedAt
+
+		if updateUsername && name.Display != account.Username.String {
+			if err := s.changeUsername(ctx, tx, account, name); err != nil {
+				return err
+			}
+		}
+
+		if updatePrimaryEmailAddress {
+			if _, err := s.setPrimaryEmailAddress(ctx, tx, account, request.PrimaryEmailAddressID); err != nil {
+				return err
+			}
+		}
+
+		account.LastModifiedAt = time.Now()
+
+		n, err := tx.UpdateAccount(ctx, *account, lastModifiedAt)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return ErrStaleAccount
+		}
+
+		// Read the account back so the caller gets the stored version token.
+		updated, err := tx.GetAccountByID(ctx, account.ID)
+		if err != nil {
+			return fmt.Errorf("failed to read back updated account: %w", err)
+		}
+
+		out.Account = newAccountDetails(*updated)
+
+		return emit(ctx, tx, EventAccountUpdated, account.ID, AccountEventData{
+			Account:       out.Account,
+			ChangedFields: request.UpdateMask,
+		})
+	})
+
+	if err != nil {
+		return nil, err
+	}
 
-func (s Service) DeleteAccount(ctx context.Context) {}
+	return out, nil
+}
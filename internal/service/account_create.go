@@ -6,70 +6,145 @@ import (
 
 	"github.com/AlpacaLabs/api-account/internal/db"
 	"github.com/AlpacaLabs/api-account/internal/db/entities"
+	"github.com/AlpacaLabs/api-account/internal/emailaddr"
+	"github.com/AlpacaLabs/api-account/internal/phonenum"
+	"github.com/AlpacaLabs/api-account/internal/username"
 	accountV1 "github.com/AlpacaLabs/protorepo-account-go/alpacalabs/account/v1"
-	"github.com/badoux/checkmail"
 	"github.com/rs/xid"
-	"github.com/ttacon/libphonenumber"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
-const (
-	MinUsernameLength = 4
-	MaxUsernameLength = 25
-)
-
-var (
-	ErrUsernameInvalidLength = fmt.Errorf("username must be between %d and %d characters long", MinUsernameLength, MaxUsernameLength)
-)
+type CreateAccountResponse struct {
+	Account AccountDetails `json:"account"`
+}
 
-func (s Service) CreateAccount(ctx context.Context, request *accountV1.CreateAccountRequest) (*accountV1.CreateAccountResponse, error) {
+// CreateAccount registers a new account with whichever of a username, primary
+// email address and phone number were given, as allowed by the signup policy.
+// The response holds everything exactly as it was stored.
+func (s Service) CreateAccount(ctx context.Context, request *accountV1.CreateAccountRequest) (*CreateAccountResponse, error) {
 	emailAddress := request.EmailAddress
-	username := request.Username
 	phoneNumber := request.PhoneNumber
 
-	// Validate email address
-	if err := checkmail.ValidateFormat(emailAddress); err != nil {
+	if err := s.signup.check(request.Username, emailAddress, phoneNumber); err != nil {
 		return nil, err
 	}
 
+	// Validate email address
+	var email emailaddr.Address
+	if emailAddress != "" {
+		var err error
+		if email, err = s.emails.Normalize(emailAddress); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		if err := s.domains.Check(email.Domain()); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
 	// Validate username
-	if username != "" {
-		if len(username) < MinUsernameLength || len(username) > MaxUsernameLength {
-			return nil, ErrUsernameInvalidLength
+	var name username.Username
+	if request.Username != "" {
+		var err error
+		if name, err = s.usernames.Check(request.Username); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
 		}
 	}
 
 	// Validate phone number
+	var phone phonenum.Number
 	if phoneNumber != "" {
-		if _, err := libphonenumber.Parse(phoneNumber, "US"); err != nil {
+		var err error
+		if phone, err = s.parseTextablePhoneNumber(ctx, phoneNumber); err != nil {
 			return nil, err
 		}
 	}
 
+	out := &CreateAccountResponse{}
+
 	err := s.dbClient.RunInTransaction(ctx, func(ctx context.Context, tx db.Transaction) error {
-		// TODO check if (confirmed or not) email address already exists
-		// TODO check if (confirmed or not) phone number already exists
-		// TODO check if account already exists with username
+		// Fail fast with a clear error. The unique indexes catch any
+		// signups that race past these checks.
+		if err := s.checkAccountIdentifiersAvailable(ctx, tx, name, email.Canonical, phone.E164); err != nil {
+			return err
+		}
 
 		accountID := xid.New().String()
 
-		if err := tx.CreateAccount(ctx, accountID, username); err != nil {
+		if err := tx.CreateAccount(ctx, accountID, name.Display, name.Folded, name.Skeleton); err != nil {
 			return err
 		}
 
-		if err := tx.CreatePhoneNumber(ctx, entities.NewPhoneNumber(entities.NewPhoneNumberInput{
-			PhoneNumber: phoneNumber,
-			AccountID:   accountID,
-		})); err != nil {
-			return err
+		// Each identifier is read back once it's created, so the caller
+		// sees the stored values, including the ones the database filled in.
+		var e *accountV1.EmailAddress
+		if emailAddress != "" {
+			entity := entities.NewEmailAddress(entities.NewEmailAddressInput{
+				Primary:               true,
+				EmailAddress:          email.Display,
+				CanonicalEmailAddress: email.Canonical,
+				CanonicalRules:        s.emails.Rules(),
+				AccountID:             accountID,
+			})
+			if err := tx.CreateEmailAddress(ctx, entity); err != nil {
+				return err
+			}
+			if err := tx.UpdatePrimaryEmailAddress(ctx, accountID, entity.ID); err != nil {
+				return err
+			}
+
+			var err error
+			if e, err = tx.GetEmailAddressByID(ctx, entity.ID); err != nil {
+				return fmt.Errorf("failed to read back created email address: %w", err)
+			}
+		}
+
+		var p *accountV1.PhoneNumber
+		if phoneNumber != "" {
+			entity := entities.NewPhoneNumber(entities.NewPhoneNumberInput{
+				PhoneNumber: phone.E164,
+				CountryCode: phone.CountryCode,
+				NumberType:  phone.Type,
+				AccountID:   accountID,
+			})
+			if err := tx.CreatePhoneNumber(ctx, entity); err != nil {
+				return err
+			}
+
+			var err error
+			if p, err = tx.GetPhoneNumberByID(ctx, entity.ID); err != nil {
+				return fmt.Errorf("failed to read back created phone number: %w", err)
+			}
+		}
+
+		a, err := tx.GetAccountByID(ctx, accountID)
+		if err != nil {
+			return fmt.Errorf("failed to read back created account: %w", err)
+		}
+
+		out.Account = newAccountDetails(*a)
+		if e != nil {
+			out.Account.EmailAddresses = []*accountV1.EmailAddress{e}
+		}
+		if p != nil {
+			out.Account.PhoneNumbers = []*accountV1.PhoneNumber{p}
 		}
 
-		if err := tx.CreateEmailAddress(ctx, entities.NewEmailAddress(entities.NewEmailAddressInput{
-			Primary:      true,
-			EmailAddress: emailAddress,
-			AccountID:    accountID,
-		})); err != nil {
+		// Let downstream services know about the new account,
+		// and ask for its contact details to be confirmed.
+		if err := emit(ctx, tx, EventAccountCreated, accountID, AccountEventData{Account: out.Account}); err != nil {
 			return err
 		}
+		if e != nil {
+			if err := s.requestEmailConfirmation(ctx, tx, e); err != nil {
+				return err
+			}
+		}
+		if p != nil {
+			if err := s.requestPhoneNumberVerification(ctx, tx, p); err != nil {
+				return err
+			}
+		}
 
 		return nil
 	})
@@ -78,12 +153,34 @@ func (s Service) CreateAccount(ctx context.Context, request *accountV1.CreateAcc
 		return nil, err
 	}
 
-	return &accountV1.CreateAccountResponse{
-		Account: &accountV1.Account{
-			// TODO populate fields
-			Id:             "",
-			EmailAddresses: nil,
-			PhoneNumbers:   nil,
-		},
-	}, nil
+	return out, nil
+}
+
+// checkAccountIdentifiersAvailable makes sure no live account already uses
+// the username, email address or phone number, whether confirmed or not.
+// The email address must be in canonical form, and the phone number in E.164.
+func (s Service) checkAccountIdentifiersAvailable(ctx context.Context, tx db.Transaction, name username.Username, canonicalEmailAddress, phoneNumber string) error {
+	if name.Display != "" {
+		if err := s.checkUsernameAvailable(ctx, tx, name, ""); err != nil {
+			return err
+		}
+	}
+
+	if canonicalEmailAddress != "" {
+		if _, err := tx.GetEmailAddressByEmailAddress(ctx, canonicalEmailAddress); err == nil {
+			return ErrEmailAddressAlreadyInUse
+		} else if err != db.ErrNotFound {
+			return err
+		}
+	}
+
+	if phoneNumber != "" {
+		if _, err := tx.GetPhoneNumberByPhoneNumber(ctx, phoneNumber); err == nil {
+			return ErrPhoneNumberAlreadyInUse
+		} else if err != db.ErrNotFound {
+			return err
+		}
+	}
+
+	return nil
 }
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/AlpacaLabs/api-account/internal/db"
+)
+
+// purgeBatchSize caps how many accounts are purged per transaction.
+const purgeBatchSize = 100
+
+type DeleteAccountRequest struct {
+	AccountID string `json:"account_id"`
+}
+
+type DeleteAccountResponse struct {
+	// RestorableUntil is the deadline for restoring the account,
+	// after which it will be permanently purged.
+	RestorableUntil time.Time `json:"restorable_until"`
+}
+
+type RestoreAccountRequest struct {
+	AccountID string `json:"account_id"`
+}
+
+type RestoreAccountResponse struct {
+	Account AccountDetails `json:"account"`
+}
+
+// DeleteAccount soft-deletes an account and all of its email addresses
+// and phone numbers. The account can be restored with RestoreAccount
+// until the configured grace period elapses.
+func (s Service) DeleteAccount(ctx context.Context, request *DeleteAccountRequest) (*DeleteAccountResponse, error) {
+	principal, err := getPrincipal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !canWrite(principal, request.AccountID) {
+		return nil, ErrUnowned
+	}
+
+	deletedAt := time.Now()
+
+	err = s.dbClient.RunInTransaction(ctx, func(ctx context.Context, tx db.Transaction) error {
+		n, err := tx.DeleteAccount(ctx, request.AccountID, deletedAt)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return db.ErrNotFound
+		}
+
+		deleted, err := tx.GetDeletedAccountByID(ctx, request.AccountID)
+		if err != nil {
+			return err
+		}
+
+		restorableUntil := deletedAt.Add(s.config.AccountDeletionGracePeriod)
+
+		return emit(ctx, tx, EventAccountDeleted, request.AccountID, AccountEventData{
+			Account:         newAccountDetails(*deleted),
+			RestorableUntil: &restorableUntil,
+		})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &DeleteAccountResponse{
+		RestorableUntil: deletedAt.Add(s.config.AccountDeletionGracePeriod),
+	}, nil
+}
+
+// RestoreAccount undoes DeleteAccount, as long as the account's grace
+// period has not yet elapsed.
+func (s Service) RestoreAccount(ctx context.Context, request *RestoreAccountRequest) (*RestoreAccountResponse, error) {
+	principal, err := getPrincipal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !canWrite(principal, request.AccountID) {
+		return nil, ErrUnowned
+	}
+
+	out := &RestoreAccountResponse{}
+
+	err = s.dbClient.RunInTransaction(ctx, func(ctx context.Context, tx db.Transaction) error {
+		account, err := tx.GetDeletedAccountByID(ctx, request.AccountID)
+		if err != nil {
+			if err == db.ErrNotFound {
+				if _, err := tx.GetAccountByID(ctx, request.AccountID); err == nil {
+					return ErrAccountNotDeleted
+				}
+			}
+			return err
+		}
+
+		deletedAt := account.DeletedAt.Time
+		if time.Since(deletedAt) > s.config.AccountDeletionGracePeriod {
+			return ErrRestoreWindowExpired
+		}
+
+		if _, err := tx.RestoreAccount(ctx, account.ID, deletedAt); err != nil {
+			return err
+		}
+
+		restored, err := tx.GetAccountByID(ctx, account.ID)
+		if err != nil {
+			return err
+		}
+
+		out.Account = newAccountDetails(*restored)
+
+		return emit(ctx, tx, EventAccountRestored, account.ID, AccountEventData{Account: out.Account})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// PurgeDeletedAccounts permanently deletes accounts whose grace period
+// has elapsed. It returns the number of accounts purged.
+func (s Service) PurgeDeletedAccounts(ctx context.Context) (int, error) {
+	deletedBefore := time.Now().Add(-s.config.AccountDeletionGracePeriod)
+
+	var total int
+	for {
+		var n int
+		err := s.dbClient.RunInTransaction(ctx, func(ctx context.Context, tx db.Transaction) error {
+			var err error
+			n, err = tx.PurgeAccounts(ctx, deletedBefore, purgeBatchSize)
+			return err
+		})
+		if err != nil {
+			return total, err
+		}
+
+		total += n
+		if n < purgeBatchSize {
+			return total, nil
+		}
+	}
+}
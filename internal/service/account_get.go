@@ -2,71 +2,119 @@ package service
 
 import (
 	"context"
+	"time"
 
 	"github.com/AlpacaLabs/api-account/internal/db"
+	"github.com/AlpacaLabs/api-account/internal/db/entities"
 	accountV1 "github.com/AlpacaLabs/protorepo-account-go/alpacalabs/account/v1"
-	paginationV1 "github.com/AlpacaLabs/protorepo-pagination-go/alpacalabs/pagination/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
-func (s Service) GetAccount(ctx context.Context, request *accountV1.GetAccountRequest) (*accountV1.GetAccountResponse, error) {
-	var accountID string
-	var emailAddresses []*accountV1.EmailAddress
-	var phoneNumbers []*accountV1.PhoneNumber
+type GetAccountResponse struct {
+	Account AccountDetails `json:"account"`
 
-	err := s.dbClient.RunInTransaction(ctx, func(ctx context.Context, tx db.Transaction) error {
-
-		// Look up the account by email address ID
-		if eid := request.GetEmailAddressId(); eid != "" {
-			emailAddress, err := tx.GetEmailAddressByID(ctx, eid)
-			if err != nil {
-				return err
-			}
-
-			accountID = emailAddress.AccountId
+	// Renamed is set when the account was found by a username it has
+	// since changed away from.
+	Renamed bool `json:"renamed,omitempty"`
+}
 
-			e, err := tx.GetEmailAddressesForAccount(ctx, accountID, paginationV1.CursorRequest{
-				// A user can't have more than 20 email addresses, right??
-				Count: 20,
-			})
-			if err != nil {
-				return err
-			}
+// GetAccount looks up an account by any one of its identifiers and
+// returns it with all of its email addresses and phone numbers.
+func (s Service) GetAccount(ctx context.Context, request *accountV1.GetAccountRequest) (*GetAccountResponse, error) {
+	principal, err := getPrincipal(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-			emailAddresses = e
-		} else if pid := request.GetPhoneNumberId(); pid != "" {
-			phoneNumber, err := tx.GetPhoneNumberByID(ctx, pid)
-			if err != nil {
-				return err
-			}
+	out := &GetAccountResponse{}
 
-			accountID = phoneNumber.AccountId
+	err = s.dbClient.RunInTransaction(ctx, func(ctx context.Context, tx db.Transaction) error {
+		account, err := s.lookUpAccount(ctx, tx, request)
+		if err == db.ErrNotFound && request.GetUsername() != "" {
+			account, err = s.lookUpRenamedAccount(ctx, tx, request.GetUsername())
+			out.Renamed = err == nil
+		}
+		if err == db.ErrNotFound {
+			return ErrAccountNotFound
+		} else if err != nil {
+			return err
+		}
 
-			p, err := tx.GetPhoneNumbersForAccount(ctx, accountID, paginationV1.CursorRequest{
-				// A user can't have more than 20 email addresses, right??
-				Count: 20,
-			})
-			if err != nil {
-				return err
-			}
+		// Callers who can't read the account get the same answer as if it
+		// didn't exist, so they can't probe for who owns an email address.
+		if !canRead(principal, account.ID) {
+			return ErrAccountNotFound
+		}
 
-			phoneNumbers = p
+		accounts := []AccountDetails{newAccountDetails(*account)}
+		if err := loadContactDetails(ctx, tx, principal, accounts); err != nil {
+			return err
 		}
 
-		// TODO support other account identifiers
+		out.Account = accounts[0]
 
 		return nil
-	})
+	}, db.ReadOnly)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return &accountV1.GetAccountResponse{
-		Account: &accountV1.Account{
-			Id:             accountID,
-			EmailAddresses: emailAddresses,
-			PhoneNumbers:   phoneNumbers,
-		},
-	}, nil
+	return out, nil
+}
 
+// lookUpAccount finds the account named by whichever identifier the request carries.
+func (s Service) lookUpAccount(ctx context.Context, tx db.Transaction, request *accountV1.GetAccountRequest) (*entities.Account, error) {
+	switch id := request.GetAccountIdentifier().(type) {
+	case *accountV1.GetAccountRequest_AccountId:
+		return tx.GetAccountByID(ctx, id.AccountId)
+	case *accountV1.GetAccountRequest_Username:
+		// Usernames that don't match exactly can still match ignoring case.
+		// They never match by skeleton: usernames that only look alike are
+		// different usernames, and skeletons are only for keeping them from
+		// being registered.
+		a, err := tx.GetAccountByUsername(ctx, s.usernames.Normalize(id.Username))
+		if err == db.ErrNotFound {
+			return tx.GetAccountByFoldedUsername(ctx, s.usernames.Fold(id.Username))
+		}
+		return a, err
+	case *accountV1.GetAccountRequest_EmailAddress:
+		e, err := s.emails.Normalize(id.EmailAddress)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return tx.GetAccountByEmailAddress(ctx, e.Canonical)
+	case *accountV1.GetAccountRequest_PhoneNumber:
+		p, err := s.phones.Parse(ctx, id.PhoneNumber)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return tx.GetAccountByPhoneNumber(ctx, p.E164)
+	case *accountV1.GetAccountRequest_EmailAddressId:
+		e, err := tx.GetEmailAddressByID(ctx, id.EmailAddressId)
+		if err != nil {
+			return nil, err
+		}
+		return tx.GetAccountByID(ctx, e.AccountId)
+	case *accountV1.GetAccountRequest_PhoneNumberId:
+		p, err := tx.GetPhoneNumberByID(ctx, id.PhoneNumberId)
+		if err != nil {
+			return nil, err
+		}
+		return tx.GetAccountByID(ctx, p.AccountId)
+	default:
+		return nil, ErrMissingAccountIdentifier
+	}
+}
+
+// lookUpRenamedAccount finds the account that gave up a username,
+// as long as the username is still on hold.
+func (s Service) lookUpRenamedAccount(ctx context.Context, tx db.Transaction, username string) (*entities.Account, error) {
+	heldSince := time.Now().Add(-s.config.UsernameHoldPeriod)
+	c, err := tx.GetUsernameChangeByUsername(ctx, s.usernames.Normalize(username), heldSince)
+	if err != nil {
+		return nil, err
+	}
+	return tx.GetAccountByID(ctx, c.AccountID)
 }
@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/AlpacaLabs/api-account/internal/db"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// MaxBatchGetAccounts caps how many identifiers a single BatchGetAccounts may resolve.
+const MaxBatchGetAccounts = 500
+
+type BatchGetAccountsRequest struct {
+	AccountIDs []string `json:"account_ids"`
+	Usernames  []string `json:"usernames"`
+
+	// IncludeContactDetails fills in each account's email addresses
+	// and phone numbers.
+	IncludeContactDetails bool `json:"include_contact_details"`
+}
+
+type BatchGetAccountsResponse struct {
+	// Results has one entry per identifier asked for, in request order,
+	// account IDs first, then usernames. An account asked for more than
+	// once appears in each of its entries.
+	Results []BatchGetAccountsResult `json:"results"`
+
+	MissingAccountIDs []string `json:"missing_account_ids"`
+	MissingUsernames  []string `json:"missing_usernames"`
+}
+
+// BatchGetAccountsResult is what one identifier in a batch resolved to.
+type BatchGetAccountsResult struct {
+	// AccountID or Username is the identifier as it was asked for.
+	AccountID string `json:"account_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+
+	// Account is nil if the identifier didn't match an account the
+	// caller can read.
+	Account *AccountDetails `json:"account"`
+
+	// Renamed is set when the account was found by a username it has
+	// since changed away from.
+	Renamed bool `json:"renamed,omitempty"`
+}
+
+// BatchGetAccounts resolves many accounts at once, with a fixed number
+// of queries however many are asked for. Identifiers that don't match
+// an account the caller can read are reported as missing instead of
+// failing the whole batch. Usernames are matched the way GetAccount
+// matches them, including by accounts that have since been renamed.
+//
+// Other services are meant to call it over gRPC, but the v1 protobuf has
+// no BatchGetAccounts RPC yet, so for now it's only served over HTTP.
+func (s Service) BatchGetAccounts(ctx context.Context, request *BatchGetAccountsRequest) (*BatchGetAccountsResponse, error) {
+	principal, err := getPrincipal(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if n := len(request.AccountIDs) + len(request.Usernames); n > MaxBatchGetAccounts {
+		return nil, status.Errorf(codes.InvalidArgument, "cannot get more than %d accounts at once; got %d", MaxBatchGetAccounts, n)
+	}
+
+	out := &BatchGetAccountsResponse{
+		Results:           []BatchGetAccountsResult{},
+		MissingAccountIDs: []string{},
+		MissingUsernames:  []string{},
+	}
+
+	// found holds each account once, so contact details are only loaded
+	// once per account. indexes says which of them each result is, or -1
+	// for none.
+	var found []AccountDetails
+	var indexes []int
+
+	err = s.dbClient.RunInTransaction(ctx, func(ctx context.Context, tx db.Transaction) error {
+		byID := make(map[string]AccountDetails)
+		byUsername := make(map[string]AccountDetails)
+
+		if len(request.AccountIDs) > 0 {
+			accounts, err := tx.GetAccountsByIDs(ctx, request.AccountIDs)
+			if err != nil {
+				return err
+			}
+			for _, a := range accounts {
+				if canRead(principal, a.ID) {
+					byID[a.ID] = newAccountDetails(*a)
+				}
+			}
+		}
+
+		// Usernames are matched in their stored form first, then ignoring
+		// case, then by accounts that gave them up, for any that didn't
+		// match the step before.
+		names := make([]string, len(request.Usernames))
+		for i, u := range request.Usernames {
+			names[i] = s.usernames.Normalize(u)
+		}
+		if len(names) > 0 {
+			accounts, err := tx.GetAccountsByUsernames(ctx, names)
+			if err != nil {
+				return err
+			}
+			for _, a := range accounts {
+				byUsername[a.Username.String] = newAccountDetails(*a)
+			}
+		}
+
+		folded := make([]string, len(request.Usernames))
+		var unmatched []string
+		for i, u := range request.Usernames {
+			if _, ok := byUsername[names[i]]; !ok {
+				folded[i] = s.usernames.Fold(u)
+				unmatched = append(unmatched, folded[i])
+			}
+		}
+		byFolded := make(map[string]AccountDetails)
+		if len(unmatched) > 0 {
+			accounts, err := tx.GetAccountsByFoldedUsernames(ctx, unmatched)
+			if err != nil {
+				return err
+			}
+			// As with GetAccount, a folded username shared by more than
+			// one account matches none of them.
+			ambiguous := make(map[string]bool)
+			for _, a := range accounts {
+				f := a.UsernameFolded.String
+				if _, ok := byFolded[f]; ok {
+					ambiguous[f] = true
+				}
+				byFolded[f] = newAccountDetails(*a)
+			}
+			for f := range ambiguous {
+				delete(byFolded, f)
+			}
+		}
+
+		var released []string
+		for i := range request.Usernames {
+			if _, ok := byUsername[names[i]]; ok {
+				continue
+			}
+			if _, ok := byFolded[folded[i]]; !ok {
+				released = append(released, names[i])
+			}
+		}
+		byReleased, err := s.lookUpRenamedAccounts(ctx, tx, released)
+		if err != nil {
+			return err
+		}
+
+		seen := make(map[string]int)
+		add := func(a AccountDetails) int {
+			if i, ok := seen[a.ID]; ok {
+				return i
+			}
+			seen[a.ID] = len(found)
+			found = append(found, a)
+			return len(found) - 1
+		}
+
+		for _, id := range request.AccountIDs {
+			out.Results = append(out.Results, BatchGetAccountsResult{AccountID: id})
+			if a, ok := byID[id]; ok {
+				indexes = append(indexes, add(a))
+			} else {
+				indexes = append(indexes, -1)
+				out.MissingAccountIDs = append(out.MissingAccountIDs, id)
+			}
+		}
+		for i, username := range request.Usernames {
+			a, ok := byUsername[names[i]]
+			if !ok {
+				a, ok = byFolded[folded[i]]
+			}
+			renamed := false
+			if !ok {
+				a, ok = byReleased[names[i]]
+				renamed = ok
+			}
+			if ok && canRead(principal, a.ID) {
+				out.Results = append(out.Results, BatchGetAccountsResult{Username: username, Renamed: renamed})
+				indexes = append(indexes, add(a))
+			} else {
+				out.Results = append(out.Results, BatchGetAccountsResult{Username: username})
+				indexes = append(indexes, -1)
+				out.MissingUsernames = append(out.MissingUsernames, username)
+			}
+		}
+
+		if !request.IncludeContactDetails {
+			return nil
+		}
+
+		return loadContactDetails(ctx, tx, principal, found)
+	}, db.ReadOnly)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for i, index := range indexes {
+		if index >= 0 {
+			out.Results[i].Account = &found[index]
+		}
+	}
+
+	return out, nil
+}
+
+// lookUpRenamedAccounts finds the accounts that gave up the given stored
+// usernames, as long as they're still on hold, keyed by username.
+// Usernames no account gave up are left out.
+func (s Service) lookUpRenamedAccounts(ctx context.Context, tx db.Transaction, usernames []string) (map[string]AccountDetails, error) {
+	out := make(map[string]AccountDetails)
+	if len(usernames) == 0 {
+		return out, nil
+	}
+
+	heldSince := time.Now().Add(-s.config.UsernameHoldPeriod)
+	changes, err := tx.GetUsernameChangesByUsernames(ctx, usernames, heldSince)
+	if err != nil || len(changes) == 0 {
+		return out, err
+	}
+
+	accountIDs := make([]string, len(changes))
+	for i, c := range changes {
+		accountIDs[i] = c.AccountID
+	}
+	accounts, err := tx.GetAccountsByIDs(ctx, accountIDs)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]AccountDetails, len(accounts))
+	for _, a := range accounts {
+		byID[a.ID] = newAccountDetails(*a)
+	}
+
+	for _, c := range changes {
+		if a, ok := byID[c.AccountID]; ok {
+			out[c.Username] = a
+		}
+	}
+
+	return out, nil
+}
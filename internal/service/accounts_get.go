@@ -1,6 +1,72 @@
 package service
 
-import "context"
+import (
+	"context"
 
-// TODO only admins can call this endpoint
-func (s Service) GetAccounts(ctx context.Context) {}
+	"github.com/AlpacaLabs/api-account/internal/db"
+	paginationV1 "github.com/AlpacaLabs/protorepo-pagination-go/alpacalabs/pagination/v1"
+)
+
+type GetAccountsRequest struct {
+	Filter        db.AccountFilter            `json:"filter"`
+	CursorRequest *paginationV1.CursorRequest `json:"cursor_request"`
+
+	// IncludeContactDetails fills in each account's email addresses
+	// and phone numbers.
+	IncludeContactDetails bool `json:"include_contact_details"`
+}
+
+type GetAccountsResponse struct {
+	Accounts       []AccountDetails `json:"accounts"`
+	CursorResponse CursorResponse   `json:"cursor_response"`
+}
+
+// GetAccounts lists every account in the system.
+// Only admins and support staff may call this; support staff
+// only get to see masked contact details.
+//
+// It's only served over HTTP for now, since the v1 protobuf has no
+// GetAccounts RPC. Wiring it into the gRPC server, and adding its
+// policy, has to wait for the protorepo to define one.
+func (s Service) GetAccounts(ctx context.Context, request *GetAccountsRequest) (*GetAccountsResponse, error) {
+	principal, err := getPrincipal(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := request.Filter
+	page, err := s.newPageRequest(request.CursorRequest, &filter)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateCreatedRange(filter.CreatedAfter, filter.CreatedBefore); err != nil {
+		return nil, err
+	}
+
+	out := &GetAccountsResponse{}
+
+	err = s.dbClient.RunInTransaction(ctx, func(ctx context.Context, tx db.Transaction) error {
+		accounts, info, err := tx.GetAccounts(ctx, filter, page.PageRequest)
+		if err != nil {
+			return err
+		}
+
+		out.Accounts = make([]AccountDetails, len(accounts))
+		for i, a := range accounts {
+			out.Accounts[i] = newAccountDetails(*a)
+		}
+		out.CursorResponse = s.cursorResponse(page, info, len(accounts))
+
+		if !request.IncludeContactDetails {
+			return nil
+		}
+
+		return loadContactDetails(ctx, tx, principal, out.Accounts)
+	}, db.ReadOnly)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
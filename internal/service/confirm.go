@@ -2,19 +2,68 @@ package service
 
 import (
 	"context"
+	"crypto/subtle"
 
 	"github.com/AlpacaLabs/api-account/internal/db"
 	accountV1 "github.com/AlpacaLabs/protorepo-account-go/alpacalabs/account/v1"
 )
 
-func (s Service) ConfirmEmailAddress(ctx context.Context, request *accountV1.ConfirmEmailAddressRequest) error {
-	return s.dbClient.RunInTransaction(ctx, func(ctx context.Context, tx db.Transaction) error {
-		return tx.ConfirmEmailAddress(ctx, request.EmailAddressId)
-	})
+type ConfirmEmailAddressWithTokenRequest struct {
+	Token string `json:"token"`
+}
+
+type ConfirmEmailAddressWithTokenResponse struct {
+	EmailAddress *accountV1.EmailAddress `json:"email_address"`
 }
 
-func (s Service) ConfirmPhoneNumber(ctx context.Context, request *accountV1.ConfirmPhoneNumberRequest) error {
-	return s.dbClient.RunInTransaction(ctx, func(ctx context.Context, tx db.Transaction) error {
-		return tx.ConfirmPhoneNumber(ctx, request.PhoneNumberId)
+// ConfirmEmailAddressWithToken confirms the email address a confirmation
+// token was sent to. Each token can only be used once, and only before
+// it expires.
+func (s Service) ConfirmEmailAddressWithToken(ctx context.Context, request *ConfirmEmailAddressWithTokenRequest) (*ConfirmEmailAddressWithTokenResponse, error) {
+	token, err := s.parseConfirmationToken(request.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &ConfirmEmailAddressWithTokenResponse{}
+
+	err = s.dbClient.RunInTransaction(ctx, func(ctx context.Context, tx db.Transaction) error {
+		t, err := tx.GetEmailConfirmationToken(ctx, token.id)
+		if err != nil {
+			// Tokens are deleted when a newer one is sent.
+			if err == db.ErrNotFound {
+				return ErrConfirmationTokenInvalid
+			}
+			return err
+		}
+
+		if subtle.ConstantTimeCompare(t.TokenHash, hashToken(request.Token)) != 1 {
+			return ErrConfirmationTokenInvalid
+		}
+
+		if n, err := tx.ConsumeEmailConfirmationToken(ctx, t.ID); err != nil {
+			return err
+		} else if n == 0 {
+			return ErrConfirmationTokenUsed
+		}
+
+		if err := tx.ConfirmEmailAddress(ctx, t.EmailAddressID); err != nil {
+			return err
+		}
+
+		e, err := tx.GetEmailAddressByID(ctx, t.EmailAddressID)
+		if err != nil {
+			return err
+		}
+
+		out.EmailAddress = e
+
+		return emit(ctx, tx, EventEmailAddressConfirmed, e.AccountId, EmailAddressEventData{EmailAddress: e})
 	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return out, nil
 }
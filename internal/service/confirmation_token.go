@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AlpacaLabs/api-account/internal/db"
+	"github.com/AlpacaLabs/api-account/internal/db/entities"
+	accountV1 "github.com/AlpacaLabs/protorepo-account-go/alpacalabs/account/v1"
+	"github.com/golang/protobuf/proto"
+	"github.com/rs/xid"
+)
+
+const (
+	keyPurposeEmailConfirmation = "email-confirmation-token"
+
+	// HeaderForConfirmationToken carries the token on messages asking
+	// for an email address to be confirmed. It's only added when the
+	// message is relayed, so the token is never stored in the outbox.
+	HeaderForConfirmationToken = "confirmation-token"
+)
+
+// A confirmation token has the form <id>.<expiry>.<nonce>.<signature>.
+// The signature covers everything before it, so the expiry can be checked
+// before touching the database, and only a hash of the token is stored.
+type confirmationToken struct {
+	id        string
+	expiresAt time.Time
+}
+
+// mintEmailConfirmationToken creates and stores a single-use confirmation
+// token for an email address, invalidating any that were sent before.
+// The returned token is what gets sent to the user.
+func (s Service) mintEmailConfirmationToken(ctx context.Context, tx db.Transaction, emailAddressID string) (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	id := xid.New().String()
+	expiresAt := now.Add(s.config.EmailConfirmationTokenTTL)
+
+	payload := strings.Join([]string{
+		id,
+		strconv.FormatInt(expiresAt.Unix(), 10),
+		base64.RawURLEncoding.EncodeToString(nonce),
+	}, ".")
+	token := payload + "." + base64.RawURLEncoding.EncodeToString(s.signConfirmationToken(payload))
+
+	if err := tx.DeleteUnconsumedEmailConfirmationTokens(ctx, emailAddressID); err != nil {
+		return "", err
+	}
+
+	if err := tx.CreateEmailConfirmationToken(ctx, entities.EmailConfirmationToken{
+		ID:             id,
+		CreatedAt:      now,
+		EmailAddressID: emailAddressID,
+		TokenHash:      hashToken(token),
+		ExpiresAt:      expiresAt,
+	}); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// parseConfirmationToken verifies the token's signature and expiry.
+func (s Service) parseConfirmationToken(token string) (confirmationToken, error) {
+	i := strings.LastIndex(token, ".")
+	if i < 0 {
+		return confirmationToken{}, ErrConfirmationTokenInvalid
+	}
+	payload := token[:i]
+
+	signature, err := base64.RawURLEncoding.DecodeString(token[i+1:])
+	if err != nil || !hmac.Equal(signature, s.signConfirmationToken(payload)) {
+		return confirmationToken{}, ErrConfirmationTokenInvalid
+	}
+
+	parts := strings.Split(payload, ".")
+	if len(parts) != 3 {
+		return confirmationToken{}, ErrConfirmationTokenInvalid
+	}
+
+	expiry, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return confirmationToken{}, ErrConfirmationTokenInvalid
+	}
+
+	t := confirmationToken{
+		id:        parts[0],
+		expiresAt: time.Unix(expiry, 0),
+	}
+
+	if time.Now().After(t.expiresAt) {
+		return t, ErrConfirmationTokenExpired
+	}
+
+	return t, nil
+}
+
+func (s Service) signConfirmationToken(payload string) []byte {
+	mac := hmac.New(sha256.New, s.deriveKey(keyPurposeEmailConfirmation))
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// requestEmailConfirmation asks for a confirmation token to be mailed to
+// the email address. The token is minted when the request is relayed.
+func (s Service) requestEmailConfirmation(ctx context.Context, tx db.Transaction, e *accountV1.EmailAddress) error {
+	return enqueueProto(ctx, tx, TopicForEmailAddressConfirmationRequested, e.AccountId, e, nil)
+}
+
+// attachEmailConfirmationToken mints a fresh confirmation token for a
+// confirmation request that's about to be relayed, invalidating any sent
+// before. It returns false if the email address has since been confirmed
+// or unregistered, in which case there's nothing to send.
+func (s Service) attachEmailConfirmationToken(ctx context.Context, tx db.Transaction, m *entities.OutboxMessage) (bool, error) {
+	requested := &accountV1.EmailAddress{}
+	if err := proto.Unmarshal(m.Payload, requested); err != nil {
+		return false, fmt.Errorf("failed to unmarshal email confirmation request: %w", err)
+	}
+
+	e, err := tx.GetEmailAddressByID(ctx, requested.Id)
+	if err == db.ErrNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	if e.Confirmed {
+		return false, nil
+	}
+
+	token, err := s.mintEmailConfirmationToken(ctx, tx, e.Id)
+	if err != nil {
+		return false, err
+	}
+
+	m.Headers[HeaderForConfirmationToken] = token
+
+	return true, nil
+}
+
+func hashToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
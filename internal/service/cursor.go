@@ -1,6 +1,251 @@
 package service
 
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/AlpacaLabs/api-account/internal/db"
+	paginationV1 "github.com/AlpacaLabs/protorepo-pagination-go/alpacalabs/pagination/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
 const (
 	DefaultPageSize = 5
 	MaxPageSize     = 1000
+
+	keyPurposeCursor = "pagination-cursor"
 )
+
+var ErrInvalidCursor = status.Error(codes.InvalidArgument, "cursor is invalid")
+
+// cursor is what clients get back to fetch another page. It's opaque to
+// them, and signed so they can't make up their own: it carries the sort
+// order and filters it was issued for, which way to page, and the sort key
+// of the row to continue from.
+type cursor struct {
+	Sort     []sortField     `json:"s,omitempty"`
+	Filter   json.RawMessage `json:"f,omitempty"`
+	Backward bool            `json:"b,omitempty"`
+	Key      db.PageKey      `json:"k"`
+}
+
+type sortField struct {
+	Field      string `json:"f"`
+	Descending bool   `json:"d,omitempty"`
+}
+
+// CursorResponse tells clients how to fetch the surrounding pages.
+// Either cursor is empty if there's nothing more in that direction.
+type CursorResponse struct {
+	PreviousCursor string `json:"previous_cursor,omitempty"`
+	NextCursor     string `json:"next_cursor,omitempty"`
+	Count          int    `json:"count"`
+
+	// HasMore reports whether there are more rows after this page.
+	// The v1 protobuf has no field for it, so gRPC clients should check
+	// for an empty NextCursor instead.
+	HasMore bool `json:"has_more"`
+}
+
+func (c CursorResponse) ToProtobuf() *paginationV1.CursorResponse {
+	return &paginationV1.CursorResponse{
+		PreviousCursor: c.PreviousCursor,
+		NextCursor:     c.NextCursor,
+		Count:          int32(c.Count),
+	}
+}
+
+// pageRequest is a validated request for a page, along with the state
+// needed to issue cursors for the pages around it.
+type pageRequest struct {
+	db.PageRequest
+
+	filter    json.RawMessage
+	firstPage bool
+}
+
+// noFilter is how a filter with nothing set marshals.
+var noFilter = []byte("{}")
+
+// newPageRequest validates a client's cursor request. Sort clauses and
+// filters may be left out when a cursor is given, since it carries them;
+// if they are given, they must match the cursor's. The filter, if not nil,
+// must be a pointer to a filter struct, which is overwritten by the cursor's.
+func (s Service) newPageRequest(request *paginationV1.CursorRequest, filter interface{}) (pageRequest, error) {
+	if request == nil {
+		return pageRequest{}, ErrNilCursorRequest
+	}
+
+	var f json.RawMessage
+	if filter != nil {
+		b, err := json.Marshal(filter)
+		if err != nil {
+			return pageRequest{}, err
+		}
+		if !bytes.Equal(b, noFilter) {
+			f = b
+		}
+	}
+
+	page := pageRequest{
+		PageRequest: db.PageRequest{
+			SortClauses: request.SortClauses,
+			Limit:       int(request.Count),
+		},
+		filter: f,
+	}
+	if page.Limit > MaxPageSize {
+		page.Limit = MaxPageSize
+	} else if page.Limit <= 0 {
+		page.Limit = DefaultPageSize
+	}
+
+	if request.Cursor == "" {
+		page.firstPage = true
+		return page, nil
+	}
+
+	c, err := s.decodeCursor(request.Cursor)
+	if err != nil {
+		return pageRequest{}, err
+	}
+
+	if len(request.SortClauses) > 0 && !reflect.DeepEqual(c.Sort, sortFields(request.SortClauses)) {
+		return pageRequest{}, status.Error(codes.InvalidArgument, "cursor was issued for a different sort order")
+	}
+	if len(f) > 0 && !bytes.Equal(c.Filter, f) {
+		return pageRequest{}, status.Error(codes.InvalidArgument, "cursor was issued for different filters")
+	}
+	if filter != nil && len(c.Filter) > 0 {
+		if err := json.Unmarshal(c.Filter, filter); err != nil {
+			return pageRequest{}, ErrInvalidCursor
+		}
+	}
+
+	page.SortClauses = sortClauses(c.Sort)
+	page.filter = c.Filter
+	page.After = c.Key
+	page.Backward = c.Backward
+
+	return page, nil
+}
+
+// cursorResponse issues the cursors for the pages before and after
+// the one that was just fetched.
+func (s Service) cursorResponse(page pageRequest, info db.PageInfo, count int) CursorResponse {
+	out := CursorResponse{Count: count}
+
+	// There's always a page on the side we came from, unless this is the
+	// first one. If this page came up empty there's nothing to page from.
+	hasPrevious, hasNext := !page.firstPage, info.HasMore
+	if page.Backward {
+		hasPrevious, hasNext = info.HasMore, true
+	}
+	if count == 0 {
+		hasPrevious, hasNext = false, false
+	}
+
+	if hasPrevious {
+		out.PreviousCursor = s.encodeCursor(page, true, info.FirstKey)
+	}
+	if hasNext {
+		out.NextCursor = s.encodeCursor(page, false, info.LastKey)
+		out.HasMore = true
+	}
+
+	return out
+}
+
+func (s Service) encodeCursor(page pageRequest, backward bool, key db.PageKey) string {
+	b, _ := json.Marshal(cursor{
+		Sort:     sortFields(page.SortClauses),
+		Filter:   page.filter,
+		Backward: backward,
+		Key:      key,
+	})
+
+	payload := base64.RawURLEncoding.EncodeToString(b)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(s.signCursor(payload))
+}
+
+func (s Service) decodeCursor(str string) (cursor, error) {
+	i := strings.LastIndex(str, ".")
+	if i < 0 {
+		return cursor{}, ErrInvalidCursor
+	}
+	payload := str[:i]
+
+	signature, err := base64.RawURLEncoding.DecodeString(str[i+1:])
+	if err != nil || !hmac.Equal(signature, s.signCursor(payload)) {
+		return cursor{}, ErrInvalidCursor
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return cursor{}, ErrInvalidCursor
+	}
+
+	var c cursor
+	if err := json.Unmarshal(b, &c); err != nil || len(c.Key) == 0 {
+		return cursor{}, ErrInvalidCursor
+	}
+
+	return c, nil
+}
+
+func (s Service) signCursor(payload string) []byte {
+	mac := hmac.New(sha256.New, s.deriveKey(keyPurposeCursor))
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+func sortFields(clauses []*paginationV1.SortClause) []sortField {
+	var out []sortField
+	for _, c := range clauses {
+		if c == nil {
+			continue
+		}
+		out = append(out, sortField{
+			Field:      c.FieldName,
+			Descending: c.Sort == paginationV1.Sort_SORT_DESC,
+		})
+	}
+	return out
+}
+
+func sortClauses(fields []sortField) []*paginationV1.SortClause {
+	var out []*paginationV1.SortClause
+	for _, f := range fields {
+		sort := paginationV1.Sort_SORT_ASC
+		if f.Descending {
+			sort = paginationV1.Sort_SORT_DESC
+		}
+		out = append(out, &paginationV1.SortClause{
+			FieldName: f.Field,
+			Sort:      sort,
+		})
+	}
+	return out
+}
+
+// checkSortVisible stops callers who only see masked values from sorting by
+// them, since cursors carry the sort key of a row in the clear.
+func checkSortVisible(clauses []*paginationV1.SortClause, masked ...string) error {
+	for _, c := range clauses {
+		if c == nil {
+			continue
+		}
+		for _, field := range masked {
+			if c.FieldName == field {
+				return status.Errorf(codes.PermissionDenied, "cannot sort by %q without permission to see it", field)
+			}
+		}
+	}
+	return nil
+}
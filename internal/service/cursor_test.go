@@ -0,0 +1,204 @@
+package service
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/AlpacaLabs/api-account/internal/db"
+	paginationV1 "github.com/AlpacaLabs/protorepo-pagination-go/alpacalabs/pagination/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func newTestService(signingKey string) Service {
+	return Service{signingKey: []byte(signingKey)}
+}
+
+var createdAtDesc = []*paginationV1.SortClause{
+	{FieldName: "created_at", Sort: paginationV1.Sort_SORT_DESC},
+}
+
+// issueCursor returns the next-page cursor for a first page
+// with the given sort and filter.
+func issueCursor(t *testing.T, s Service, sort []*paginationV1.SortClause, filter interface{}) string {
+	t.Helper()
+
+	page, err := s.newPageRequest(&paginationV1.CursorRequest{SortClauses: sort}, filter)
+	if err != nil {
+		t.Fatalf("newPageRequest() error = %v", err)
+	}
+
+	out := s.cursorResponse(page, db.PageInfo{
+		FirstKey: db.PageKey{"2020-01-02T00:00:00Z", "b"},
+		LastKey:  db.PageKey{"2020-01-01T00:00:00Z", "a"},
+		HasMore:  true,
+	}, 2)
+	if out.NextCursor == "" {
+		t.Fatal("cursorResponse() issued no next cursor")
+	}
+
+	return out.NextCursor
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	s := newTestService("secret")
+	confirmed := true
+	c := issueCursor(t, s, createdAtDesc, &db.EmailAddressFilter{Confirmed: &confirmed})
+
+	// The cursor carries the sort and filter, so they can be left out.
+	var filter db.EmailAddressFilter
+	page, err := s.newPageRequest(&paginationV1.CursorRequest{Cursor: c}, &filter)
+	if err != nil {
+		t.Fatalf("newPageRequest() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(page.After, db.PageKey{"2020-01-01T00:00:00Z", "a"}) {
+		t.Errorf("After = %v, want the last key of the previous page", page.After)
+	}
+	if page.Backward {
+		t.Error("Backward = true, want false for a next-page cursor")
+	}
+	if !reflect.DeepEqual(sortFields(page.SortClauses), sortFields(createdAtDesc)) {
+		t.Errorf("SortClauses = %v, want %v", page.SortClauses, createdAtDesc)
+	}
+	if filter.Confirmed == nil || !*filter.Confirmed {
+		t.Errorf("filter was not restored from the cursor: %+v", filter)
+	}
+}
+
+func TestCursorRejectsTampering(t *testing.T) {
+	s := newTestService("secret")
+	c := issueCursor(t, s, createdAtDesc, nil)
+	i := strings.LastIndex(c, ".")
+	payload, signature := c[:i], c[i+1:]
+
+	// flip changes one character without leaving the base64 alphabet.
+	flip := func(str string, at int) string {
+		b := []byte(str)
+		if b[at] == 'A' {
+			b[at] = 'B'
+		} else {
+			b[at] = 'A'
+		}
+		return string(b)
+	}
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"tampered payload", flip(payload, 0) + "." + signature},
+		{"tampered signature", payload + "." + flip(signature, 0)},
+		{"missing signature", payload},
+		{"empty signature", payload + "."},
+		{"signature is not base64", payload + ".!!!"},
+		{"signed with another key", issueCursor(t, newTestService("other"), createdAtDesc, nil)},
+		{"garbage", "not-a-cursor"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.newPageRequest(&paginationV1.CursorRequest{Cursor: tt.cursor}, nil)
+			if err != ErrInvalidCursor {
+				t.Errorf("newPageRequest() error = %v, want %v", err, ErrInvalidCursor)
+			}
+		})
+	}
+}
+
+func TestCursorMustMatchRequest(t *testing.T) {
+	s := newTestService("secret")
+	confirmed := true
+	c := issueCursor(t, s, createdAtDesc, &db.EmailAddressFilter{Confirmed: &confirmed})
+
+	unconfirmed := false
+	tests := []struct {
+		name   string
+		sort   []*paginationV1.SortClause
+		filter interface{}
+	}{
+		{
+			name: "different sort",
+			sort: []*paginationV1.SortClause{{FieldName: "created_at", Sort: paginationV1.Sort_SORT_ASC}},
+		},
+		{
+			name:   "different filter",
+			filter: &db.EmailAddressFilter{Confirmed: &unconfirmed},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.newPageRequest(&paginationV1.CursorRequest{Cursor: c, SortClauses: tt.sort}, tt.filter)
+			if status.Code(err) != codes.InvalidArgument || err == ErrInvalidCursor {
+				t.Errorf("newPageRequest() error = %v, want a mismatch error", err)
+			}
+		})
+	}
+}
+
+func TestPageRequestLimit(t *testing.T) {
+	s := newTestService("secret")
+
+	tests := []struct {
+		count int32
+		want  int
+	}{
+		{0, DefaultPageSize},
+		{-1, DefaultPageSize},
+		{10, 10},
+		{MaxPageSize + 1, MaxPageSize},
+	}
+
+	for _, tt := range tests {
+		page, err := s.newPageRequest(&paginationV1.CursorRequest{Count: tt.count}, nil)
+		if err != nil {
+			t.Fatalf("newPageRequest() error = %v", err)
+		}
+		if page.Limit != tt.want {
+			t.Errorf("count %d: Limit = %d, want %d", tt.count, page.Limit, tt.want)
+		}
+	}
+
+	if _, err := s.newPageRequest(nil, nil); err != ErrNilCursorRequest {
+		t.Errorf("newPageRequest(nil) error = %v, want %v", err, ErrNilCursorRequest)
+	}
+}
+
+func TestCursorResponse(t *testing.T) {
+	s := newTestService("secret")
+	info := db.PageInfo{FirstKey: db.PageKey{"b"}, LastKey: db.PageKey{"a"}}
+
+	tests := []struct {
+		name         string
+		page         pageRequest
+		hasMore      bool
+		count        int
+		wantPrevious bool
+		wantNext     bool
+	}{
+		{"only page", pageRequest{firstPage: true}, false, 2, false, false},
+		{"first of several", pageRequest{firstPage: true}, true, 2, false, true},
+		{"last page forward", pageRequest{}, false, 2, true, false},
+		{"middle page backward", pageRequest{PageRequest: db.PageRequest{Backward: true}}, true, 2, true, true},
+		{"first page reached backward", pageRequest{PageRequest: db.PageRequest{Backward: true}}, false, 2, false, true},
+		{"empty page", pageRequest{}, true, 0, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info.HasMore = tt.hasMore
+			out := s.cursorResponse(tt.page, info, tt.count)
+			if got := out.PreviousCursor != ""; got != tt.wantPrevious {
+				t.Errorf("has previous cursor = %v, want %v", got, tt.wantPrevious)
+			}
+			if got := out.NextCursor != ""; got != tt.wantNext {
+				t.Errorf("has next cursor = %v, want %v", got, tt.wantNext)
+			}
+			if out.HasMore != tt.wantNext {
+				t.Errorf("HasMore = %v, want %v", out.HasMore, tt.wantNext)
+			}
+		})
+	}
+}
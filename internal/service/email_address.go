@@ -2,6 +2,12 @@ package service
 
 import (
 	"context"
+	"time"
+
+	"github.com/AlpacaLabs/api-account/internal/db"
+	"github.com/AlpacaLabs/api-account/internal/db/entities"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // UpdateEmailAddress updates the email address's confirmation status.
@@ -13,3 +19,88 @@ func (s Service) UpdateEmailAddress(ctx context.Context) {
 	// Update the email's confirmation status
 	// Return new entity in response
 }
+
+// NormalizeEmailAddresses works out the canonical form of email addresses
+// stored under other rules, e.g. before provider rules were turned on.
+// An address whose canonical form another address already has keeps its
+// old one, and the conflict is recorded until one of them goes away.
+// It returns how many addresses changed, and the conflicts found.
+// If another instance is already normalizing, it does nothing.
+func (s Service) NormalizeEmailAddresses(ctx context.Context) (int, []entities.EmailAddressConflict, error) {
+	var total int
+	var conflicts []entities.EmailAddressConflict
+
+	_, err := s.dbClient.RunExclusively(ctx, db.NormalizeEmailAddressesLockID, func(ctx context.Context) error {
+		rules := s.emails.Rules()
+		var afterID string
+		for {
+			var emailAddresses []entities.EmailAddress
+			err := s.dbClient.RunInTransaction(ctx, func(ctx context.Context, tx db.Transaction) error {
+				var err error
+				emailAddresses, err = tx.GetUncanonicalizedEmailAddresses(ctx, rules, afterID, normalizeBatchSize)
+				return err
+			}, db.ReadOnly)
+			if err != nil {
+				return err
+			}
+
+			for _, e := range emailAddresses {
+				afterID = e.ID
+
+				// Addresses that no longer validate keep their canonical form.
+				canonical := e.CanonicalEmailAddress
+				if address, err := s.emails.Normalize(e.EmailAddress); err == nil {
+					canonical = address.Canonical
+				}
+
+				// Each address gets its own transaction, so one that collides
+				// with another address doesn't hold up the rest.
+				var conflict *entities.EmailAddressConflict
+				err := s.dbClient.RunInTransaction(ctx, func(ctx context.Context, tx db.Transaction) error {
+					// Only addresses in use have to have a canonical form of their own.
+					if canonical != e.CanonicalEmailAddress && !e.DeletedAt.Valid {
+						other, err := tx.GetEmailAddressByEmailAddress(ctx, canonical)
+						if err != nil && err != db.ErrNotFound {
+							return err
+						}
+						if other != nil && other.Id != e.ID {
+							conflict = &entities.EmailAddressConflict{
+								EmailAddressID:            e.ID,
+								AccountID:                 e.AccountID,
+								CanonicalEmailAddress:     canonical,
+								ConflictingEmailAddressID: other.Id,
+								ConflictingAccountID:      other.AccountId,
+								DetectedAt:                time.Now(),
+							}
+							return tx.PutEmailAddressConflict(ctx, *conflict)
+						}
+					}
+
+					if err := tx.UpdateCanonicalEmailAddress(ctx, e.ID, canonical, rules); err != nil {
+						return err
+					}
+					return tx.DeleteEmailAddressConflict(ctx, e.ID)
+				})
+				if st, ok := status.FromError(err); ok && st.Code() == codes.AlreadyExists {
+					// The canonical form was taken after we looked.
+					// The address is tried again next time.
+					continue
+				} else if err != nil {
+					return err
+				}
+
+				if conflict != nil {
+					conflicts = append(conflicts, *conflict)
+				} else if canonical != e.CanonicalEmailAddress {
+					total++
+				}
+			}
+
+			if len(emailAddresses) < normalizeBatchSize {
+				return nil
+			}
+		}
+	})
+
+	return total, conflicts, err
+}
@@ -9,18 +9,26 @@ import (
 
 // GetEmailAddress retrieves an email address by primary key.
 func (s Service) GetEmailAddress(ctx context.Context, request *accountV1.GetEmailAddressRequest) (*accountV1.GetEmailAddressResponse, error) {
-	requesterID := getRequesterID(ctx)
+	principal, err := getPrincipal(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	response := &accountV1.GetEmailAddressResponse{}
-	err := s.dbClient.RunInTransaction(ctx, func(ctx context.Context, tx db.Transaction) error {
+	err = s.dbClient.RunInTransaction(ctx, func(ctx context.Context, tx db.Transaction) error {
 		emailAddress, err := tx.GetEmailAddressByID(ctx, request.Id)
 		if err != nil {
 			return err
 		}
 
-		if emailAddress.AccountId != requesterID {
+		if !canRead(principal, emailAddress.AccountId) {
 			return ErrUnowned
 		}
 
+		if mustMask(principal, emailAddress.AccountId) {
+			maskEmailAddress(emailAddress)
+		}
+
 		response.EmailAddress = emailAddress
 		return nil
 	})
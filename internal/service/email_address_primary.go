@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/AlpacaLabs/api-account/internal/db"
+	"github.com/AlpacaLabs/api-account/internal/db/entities"
+	accountV1 "github.com/AlpacaLabs/protorepo-account-go/alpacalabs/account/v1"
+	"github.com/guregu/null"
+)
+
+type SetPrimaryEmailAddressRequest struct {
+	AccountID      string `json:"account_id"`
+	EmailAddressID string `json:"email_address_id"`
+}
+
+type SetPrimaryEmailAddressResponse struct {
+	Account      AccountDetails          `json:"account"`
+	EmailAddress *accountV1.EmailAddress `json:"email_address"`
+}
+
+// SetPrimaryEmailAddress makes a confirmed email address the account's
+// primary one. The old primary stays registered as a secondary address.
+func (s Service) SetPrimaryEmailAddress(ctx context.Context, request *SetPrimaryEmailAddressRequest) (*SetPrimaryEmailAddressResponse, error) {
+	principal, err := getPrincipal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !canWrite(principal, request.AccountID) {
+		return nil, ErrUnowned
+	}
+
+	out := &SetPrimaryEmailAddressResponse{}
+
+	err = s.dbClient.RunInTransaction(ctx, func(ctx context.Context, tx db.Transaction) error {
+		account, err := tx.GetAccountByID(ctx, request.AccountID)
+		if err != nil {
+			return err
+		}
+
+		lastModifiedAt := account.LastModifiedAt
+
+		e, err := s.setPrimaryEmailAddress(ctx, tx, account, request.EmailAddressID)
+		if err != nil {
+			return err
+		}
+		out.EmailAddress = e
+
+		// Nothing to do if it's already the primary.
+		if !account.LastModifiedAt.After(lastModifiedAt) {
+			out.Account = newAccountDetails(*account)
+			return nil
+		}
+
+		if n, err := tx.UpdateAccount(ctx, *account, lastModifiedAt); err != nil {
+			return err
+		} else if n == 0 {
+			return ErrStaleAccount
+		}
+
+		out.Account = newAccountDetails(*account)
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// setPrimaryEmailAddress switches the account's primary email address,
+// updating the account in place. The caller must persist the account,
+// which is how its version gets bumped.
+func (s Service) setPrimaryEmailAddress(ctx context.Context, tx db.Transaction, account *entities.Account, emailAddressID string) (*accountV1.EmailAddress, error) {
+	e, err := tx.GetEmailAddressByID(ctx, emailAddressID)
+	if err != nil {
+		return nil, err
+	}
+	if e.AccountId != account.ID {
+		return nil, ErrUnowned
+	}
+	if emailAddressID == account.PrimaryEmailAddressID.String && e.Primary {
+		return e, nil
+	}
+	if !e.Confirmed {
+		return nil, ErrPrimaryEmailAddressUnconfirmed
+	}
+
+	previousID := account.PrimaryEmailAddressID.String
+
+	if err := tx.UpdatePrimaryEmailAddress(ctx, account.ID, e.Id); err != nil {
+		return nil, err
+	}
+
+	account.PrimaryEmailAddressID = null.StringFrom(e.Id)
+	account.LastModifiedAt = time.Now()
+	e.Primary = true
+
+	if err := emit(ctx, tx, EventPrimaryEmailChanged, account.ID, EmailAddressEventData{
+		EmailAddress:                  e,
+		PreviousPrimaryEmailAddressID: previousID,
+	}); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
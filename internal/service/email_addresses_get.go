@@ -2,51 +2,95 @@ package service
 
 import (
 	"context"
+	"strings"
 
+	"github.com/AlpacaLabs/api-account/internal/auth"
 	"github.com/AlpacaLabs/api-account/internal/db"
 	accountV1 "github.com/AlpacaLabs/protorepo-account-go/alpacalabs/account/v1"
 	paginationV1 "github.com/AlpacaLabs/protorepo-pagination-go/alpacalabs/pagination/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+type ListEmailAddressesRequest struct {
+	Filter        db.EmailAddressFilter       `json:"filter"`
+	CursorRequest *paginationV1.CursorRequest `json:"cursor_request"`
+}
+
+type ListEmailAddressesResponse struct {
+	EmailAddresses []*accountV1.EmailAddress `json:"email_addresses"`
+	CursorResponse CursorResponse            `json:"cursor_response"`
+}
+
 // GetEmailAddresses retrieves all email addresses in the system.
-// Ideally, this function should be locked down and offered for
-// admins only.
+// Only admins and support staff may call this; support staff
+// only get to see masked email addresses.
 func (s Service) GetEmailAddresses(ctx context.Context, request *accountV1.GetEmailAddressesRequest) (*accountV1.GetEmailAddressesResponse, error) {
-	out := &accountV1.GetEmailAddressesResponse{}
-
-	// Validate cursor
-	if request.CursorRequest == nil {
-		return nil, ErrNilCursorRequest
+	response, err := s.ListEmailAddresses(ctx, &ListEmailAddressesRequest{
+		CursorRequest: request.CursorRequest,
+	})
+	if err != nil {
+		return nil, err
 	}
-	cursorRequest := *request.CursorRequest
-	if cursorRequest.Count > MaxPageSize {
-		cursorRequest.Count = MaxPageSize
-	} else if cursorRequest.Count == 0 {
-		cursorRequest.Count = DefaultPageSize
+
+	return &accountV1.GetEmailAddressesResponse{
+		EmailAddresses: response.EmailAddresses,
+		CursorResponse: response.CursorResponse.ToProtobuf(),
+	}, nil
+}
+
+// ListEmailAddresses is GetEmailAddresses with filters.
+func (s Service) ListEmailAddresses(ctx context.Context, request *ListEmailAddressesRequest) (*ListEmailAddressesResponse, error) {
+	principal, err := getPrincipal(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	err := s.dbClient.RunInTransaction(ctx, func(ctx context.Context, tx db.Transaction) error {
-		emailAddresses, err := tx.GetEmailAddresses(ctx, cursorRequest)
+	filter := request.Filter
+	if filter.Domain != "" {
+		domain, err := s.emails.NormalizeDomain(strings.TrimPrefix(filter.Domain, "@"))
 		if err != nil {
-			return err
+			return nil, status.Error(codes.InvalidArgument, err.Error())
 		}
+		filter.Domain = domain
+	}
+	page, err := s.newPageRequest(request.CursorRequest, &filter)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateCreatedRange(filter.CreatedAfter, filter.CreatedBefore); err != nil {
+		return nil, err
+	}
 
-		out.EmailAddresses = emailAddresses
+	// Support staff see masked addresses, so they can't sort by them.
+	// They can still filter by domain, which is what they need to find
+	// addresses by, and which masking only partly hides.
+	masked := !principal.HasRole(auth.RoleAdmin)
+	if masked {
+		if err := checkSortVisible(page.SortClauses, "email_address"); err != nil {
+			return nil, err
+		}
+	}
 
-		count := len(emailAddresses)
+	out := &ListEmailAddressesResponse{}
 
-		out.CursorResponse = &paginationV1.CursorResponse{
-			PreviousCursor: cursorRequest.Cursor,
-			Count:          int32(count),
+	err = s.dbClient.RunInTransaction(ctx, func(ctx context.Context, tx db.Transaction) error {
+		emailAddresses, info, err := tx.GetEmailAddresses(ctx, filter, page.PageRequest)
+		if err != nil {
+			return err
 		}
 
-		if count > 0 {
-			// Set the next cursor clients should use so they can continue pagination
-			out.CursorResponse.NextCursor = emailAddresses[count-1].Id
+		if masked {
+			for _, e := range emailAddresses {
+				maskEmailAddress(e)
+			}
 		}
 
+		out.EmailAddresses = emailAddresses
+		out.CursorResponse = s.cursorResponse(page, info, len(emailAddresses))
+
 		return nil
-	})
+	}, db.ReadOnly)
 
 	if err != nil {
 		return nil, err
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/AlpacaLabs/api-account/internal/db"
+	"github.com/AlpacaLabs/api-account/internal/db/entities"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// EmailDomainRule is an admin-managed rule that allows or denies email
+// addresses at a domain and its subdomains.
+type EmailDomainRule struct {
+	Domain    string    `json:"domain"`
+	Action    string    `json:"action"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	CreatedBy string    `json:"created_by,omitempty"`
+}
+
+func newEmailDomainRule(r entities.EmailDomainRule) EmailDomainRule {
+	return EmailDomainRule{
+		Domain:    r.Domain,
+		Action:    r.Action,
+		Reason:    r.Reason,
+		CreatedAt: r.CreatedAt,
+		CreatedBy: r.CreatedBy,
+	}
+}
+
+type GetEmailDomainRulesResponse struct {
+	Rules []EmailDomainRule `json:"rules"`
+}
+
+type PutEmailDomainRuleRequest struct {
+	Domain string `json:"domain"`
+
+	// Action is either "allow" or "deny".
+	Action string `json:"action"`
+
+	// Reason is shown to users whose email address is denied.
+	Reason string `json:"reason"`
+}
+
+type PutEmailDomainRuleResponse struct {
+	Rule EmailDomainRule `json:"rule"`
+}
+
+type DeleteEmailDomainRuleRequest struct {
+	Domain string `json:"domain"`
+}
+
+type DeleteEmailDomainRuleResponse struct{}
+
+// GetEmailDomainRules lists the admin-managed email domain rules.
+func (s Service) GetEmailDomainRules(ctx context.Context) (*GetEmailDomainRulesResponse, error) {
+	out := &GetEmailDomainRulesResponse{Rules: []EmailDomainRule{}}
+
+	err := s.dbClient.RunInTransaction(ctx, func(ctx context.Context, tx db.Transaction) error {
+		rules, err := tx.GetEmailDomainRules(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, r := range rules {
+			out.Rules = append(out.Rules, newEmailDomainRule(r))
+		}
+
+		return nil
+	}, db.ReadOnly)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// PutEmailDomainRule sets the rule for a domain, replacing any existing one.
+// It takes effect on this instance at once, and on the others once they
+// refresh their domain policy.
+func (s Service) PutEmailDomainRule(ctx context.Context, request *PutEmailDomainRuleRequest) (*PutEmailDomainRuleResponse, error) {
+	principal, err := getPrincipal(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	domain, err := s.normalizeRuleDomain(request.Domain)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.Action != entities.EmailDomainActionAllow && request.Action != entities.EmailDomainActionDeny {
+		return nil, status.Errorf(codes.InvalidArgument, "action must be %q or %q", entities.EmailDomainActionAllow, entities.EmailDomainActionDeny)
+	}
+
+	rule := entities.EmailDomainRule{
+		Domain:    domain,
+		Action:    request.Action,
+		Reason:    strings.TrimSpace(request.Reason),
+		CreatedAt: time.Now(),
+		CreatedBy: principal.AccountID,
+	}
+
+	err = s.dbClient.RunInTransaction(ctx, func(ctx context.Context, tx db.Transaction) error {
+		return tx.PutEmailDomainRule(ctx, rule)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.domains.PutRule(rule)
+
+	return &PutEmailDomainRuleResponse{Rule: newEmailDomainRule(rule)}, nil
+}
+
+// DeleteEmailDomainRule removes the rule for a domain.
+func (s Service) DeleteEmailDomainRule(ctx context.Context, request *DeleteEmailDomainRuleRequest) (*DeleteEmailDomainRuleResponse, error) {
+	domain, err := s.normalizeRuleDomain(request.Domain)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.dbClient.RunInTransaction(ctx, func(ctx context.Context, tx db.Transaction) error {
+		n, err := tx.DeleteEmailDomainRule(ctx, domain)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return db.ErrNotFound
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.domains.DeleteRule(domain)
+
+	return &DeleteEmailDomainRuleResponse{}, nil
+}
+
+// RefreshEmailDomainPolicy reloads the admin-managed email domain rules
+// from the database, and the disposable domains from their file if one is
+// configured. If either can't be read, the policy keeps what it had.
+func (s Service) RefreshEmailDomainPolicy(ctx context.Context) error {
+	if err := s.loadDisposableDomains(); err != nil {
+		return err
+	}
+
+	var rules []entities.EmailDomainRule
+
+	err := s.dbClient.RunInTransaction(ctx, func(ctx context.Context, tx db.Transaction) error {
+		var err error
+		rules, err = tx.GetEmailDomainRules(ctx)
+		return err
+	}, db.ReadOnly)
+
+	if err != nil {
+		return err
+	}
+
+	s.domains.SetRules(rules)
+
+	return nil
+}
+
+// loadDisposableDomains reads the configured list of disposable domains.
+// Without one, the list built into the binary stays in use.
+func (s Service) loadDisposableDomains() error {
+	if s.config.EmailDisposableDomainsFile == "" {
+		return nil
+	}
+
+	b, err := ioutil.ReadFile(s.config.EmailDisposableDomainsFile)
+	if err != nil {
+		return fmt.Errorf("failed to read disposable email domains file: %w", err)
+	}
+
+	s.domains.SetDisposableDomains(string(b))
+
+	return nil
+}
+
+func (s Service) normalizeRuleDomain(domain string) (string, error) {
+	domain = strings.TrimPrefix(strings.TrimSpace(domain), "@")
+	if domain == "" {
+		return "", status.Error(codes.InvalidArgument, "domain is required")
+	}
+
+	d, err := s.emails.NormalizeDomain(domain)
+	if err != nil {
+		return "", status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return d, nil
+}
@@ -1,16 +1,48 @@
 package service
 
-import "errors"
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
 
 var (
-	ErrEmailAlreadyRegisteredByDifferentAccount = errors.New("that email address is already registered by a different account")
-	ErrPhoneAlreadyRegisteredByDifferentAccount = errors.New("that phone number is already registered by a different account")
+	ErrUnauthenticated = status.Error(codes.Unauthenticated, "request must carry a valid bearer token")
+
+	ErrEmailAlreadyRegisteredByDifferentAccount = status.Error(codes.AlreadyExists, "that email address is already registered by a different account")
+	ErrPhoneAlreadyRegisteredByDifferentAccount = status.Error(codes.AlreadyExists, "that phone number is already registered by a different account")
+
+	ErrUnowned = status.Error(codes.PermissionDenied, "you do not own that resource")
+
+	ErrUnregisterPrimaryEmailAddress = status.Error(codes.FailedPrecondition, "cannot unregister primary email address; make another address primary first")
+	ErrUnregisterUnownedEmailAddress = status.Error(codes.PermissionDenied, "cannot unregister email address you do not own")
+	ErrUnregisterUnownedPhoneNumber  = status.Error(codes.PermissionDenied, "cannot unregister phone number you do not own")
+
+	ErrNilCursorRequest = status.Error(codes.InvalidArgument, "client must provide non-nil cursor info for pagination")
+
+	ErrMissingLastModifiedAt          = status.Error(codes.InvalidArgument, "last_modified_at of the account being updated is required")
+	ErrStaleAccount                   = status.Error(codes.Aborted, "account has been modified since it was read; reload it and try again")
+	ErrUsernameAlreadyTaken           = status.Error(codes.AlreadyExists, "username is already in use")
+	ErrUsernameTooSimilar             = status.Error(codes.AlreadyExists, "username is too similar to one that is already in use")
+	ErrUsernameOnHold                 = status.Error(codes.AlreadyExists, "username was recently used by another account and can't be claimed yet")
+	ErrPrimaryEmailAddressUnconfirmed = status.Error(codes.FailedPrecondition, "only confirmed email addresses can be made primary")
+
+	ErrMissingAccountIdentifier = status.Error(codes.InvalidArgument, "an account identifier is required")
+	ErrAccountNotFound          = status.Error(codes.NotFound, "no account matches that identifier")
+
+	ErrEmailAddressAlreadyInUse = status.Error(codes.AlreadyExists, "email_address is already in use")
+	ErrPhoneNumberAlreadyInUse  = status.Error(codes.AlreadyExists, "phone_number is already in use")
+
+	ErrPhoneNumberCannotReceiveSMS = status.Error(codes.InvalidArgument, "landline numbers can't receive text messages; use a mobile number")
 
-	ErrUnowned = errors.New("you do not own that resource")
+	ErrAccountNotDeleted    = status.Error(codes.FailedPrecondition, "account has not been deleted")
+	ErrRestoreWindowExpired = status.Error(codes.FailedPrecondition, "account can no longer be restored")
 
-	ErrUnregisterPrimaryEmailAddress = errors.New("cannot unregister primary email address")
-	ErrUnregisterUnownedEmailAddress = errors.New("cannot unregister email address you do not own")
-	ErrUnregisterUnownedPhoneNumber  = errors.New("cannot unregister phone number you do not own")
+	ErrConfirmationTokenInvalid = status.Error(codes.InvalidArgument, "confirmation token is invalid")
+	ErrConfirmationTokenExpired = status.Error(codes.FailedPrecondition, "confirmation token has expired")
+	ErrConfirmationTokenUsed    = status.Error(codes.AlreadyExists, "confirmation token has already been used")
 
-	ErrNilCursorRequest = errors.New("client must provide non-nil cursor info for pagination")
+	ErrVerificationCodeNotRequested = status.Error(codes.FailedPrecondition, "no verification code has been sent to that phone number")
+	ErrVerificationCodeExpired      = status.Error(codes.FailedPrecondition, "verification code has expired; request a new one")
+	ErrVerificationCodeLockedOut    = status.Error(codes.ResourceExhausted, "too many incorrect verification codes; try again later")
+	ErrVerificationCodeIncorrect    = status.Error(codes.InvalidArgument, "verification code is incorrect")
 )
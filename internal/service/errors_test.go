@@ -0,0 +1,29 @@
+package service
+
+import (
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestErrorCodes(t *testing.T) {
+	tests := []struct {
+		err  error
+		want codes.Code
+	}{
+		{ErrUnowned, codes.PermissionDenied},
+		{ErrUnregisterUnownedEmailAddress, codes.PermissionDenied},
+		{ErrUnregisterUnownedPhoneNumber, codes.PermissionDenied},
+		{ErrUnregisterPrimaryEmailAddress, codes.FailedPrecondition},
+		{ErrEmailAlreadyRegisteredByDifferentAccount, codes.AlreadyExists},
+		{ErrPhoneAlreadyRegisteredByDifferentAccount, codes.AlreadyExists},
+		{ErrNilCursorRequest, codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		if got := status.Code(tt.err); got != tt.want {
+			t.Errorf("%v: code = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
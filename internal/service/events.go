@@ -0,0 +1,202 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/AlpacaLabs/api-account/internal/auth"
+	"github.com/AlpacaLabs/api-account/internal/db"
+	accountV1 "github.com/AlpacaLabs/protorepo-account-go/alpacalabs/account/v1"
+	"github.com/golang/protobuf/proto"
+	"github.com/rs/xid"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// TopicForAccountEvents carries every domain event this service emits.
+// Events are keyed by account ID, so all events for a given account land
+// on the same partition and are consumed in the order they happened.
+const TopicForAccountEvents = "account-events"
+
+// EventVersion is the version of the Event envelope and of the data each
+// event type carries. It's bumped on any change consumers must know about.
+const EventVersion = 1
+
+// Domain event types. The data each one carries is documented next to it.
+//
+// Events are published as JSON rather than as protobuf messages: the
+// protorepo this service builds against defines no event messages, and
+// can't be regenerated from here. Their types are named so as not to be
+// mistaken for protobuf message names. Once the protorepo has event
+// messages, they'll be published under a new EventVersion.
+const (
+	// Account events carry AccountEventData.
+	EventAccountCreated  = "account.created"
+	EventAccountUpdated  = "account.updated"
+	EventAccountDeleted  = "account.deleted"
+	EventAccountRestored = "account.restored"
+
+	// Email address events carry EmailAddressEventData.
+	EventEmailAddressRegistered   = "email_address.registered"
+	EventEmailAddressConfirmed    = "email_address.confirmed"
+	EventEmailAddressUnregistered = "email_address.unregistered"
+	EventPrimaryEmailChanged      = "email_address.made_primary"
+
+	// Phone number events carry PhoneNumberEventData.
+	EventPhoneNumberRegistered   = "phone_number.registered"
+	EventPhoneNumberConfirmed    = "phone_number.confirmed"
+	EventPhoneNumberUnregistered = "phone_number.unregistered"
+)
+
+// Kafka headers carried by every domain event, so consumers can route
+// events without decoding them.
+const (
+	HeaderForEventID      = "event-id"
+	HeaderForEventType    = "event-type"
+	HeaderForEventVersion = "event-version"
+	HeaderForAccountID    = "account-id"
+	HeaderForContentType  = "content-type"
+)
+
+// Event is the JSON envelope every domain event is published in.
+// Protobuf values in its data are encoded with protobuf's JSON mapping.
+type Event struct {
+	Version    int       `json:"version"`
+	ID         string    `json:"id"`
+	Type       string    `json:"type"`
+	AccountID  string    `json:"account_id"`
+	OccurredAt time.Time `json:"occurred_at"`
+
+	// ActorID is the account ID of the principal that caused the event.
+	// It's empty for anonymous actions, such as signing up.
+	ActorID string `json:"actor_id,omitempty"`
+
+	// Data is the state of whatever the event is about, after it happened.
+	Data interface{} `json:"data"`
+}
+
+// AccountEventData is the account as it was left by the event.
+type AccountEventData struct {
+	Account AccountDetails `json:"account"`
+
+	// ChangedFields lists the fields an AccountUpdated event changed.
+	ChangedFields []string `json:"changed_fields,omitempty"`
+
+	// RestorableUntil is when an AccountDeleted account gets purged.
+	RestorableUntil *time.Time `json:"restorable_until,omitempty"`
+}
+
+// EmailAddressEventData is the email address as it was left by the event.
+type EmailAddressEventData struct {
+	EmailAddress *accountV1.EmailAddress `json:"email_address"`
+
+	// PreviousPrimaryEmailAddressID is the address a PrimaryEmailChanged
+	// event replaced, if there was one.
+	PreviousPrimaryEmailAddressID string `json:"previous_primary_email_address_id,omitempty"`
+}
+
+// PhoneNumberEventData is the phone number as it was left by the event.
+type PhoneNumberEventData struct {
+	PhoneNumber *accountV1.PhoneNumber `json:"phone_number"`
+}
+
+func (d AccountEventData) MarshalJSON() ([]byte, error) {
+	// The contact details shadow the embedded ones, which encoding/json
+	// would otherwise encode field by field.
+	type account struct {
+		AccountDetails
+		EmailAddresses []json.RawMessage `json:"email_addresses,omitempty"`
+		PhoneNumbers   []json.RawMessage `json:"phone_numbers,omitempty"`
+	}
+
+	a := account{AccountDetails: d.Account}
+	for _, e := range d.Account.EmailAddresses {
+		b, err := protoJSON(e)
+		if err != nil {
+			return nil, err
+		}
+		a.EmailAddresses = append(a.EmailAddresses, b)
+	}
+	for _, p := range d.Account.PhoneNumbers {
+		b, err := protoJSON(p)
+		if err != nil {
+			return nil, err
+		}
+		a.PhoneNumbers = append(a.PhoneNumbers, b)
+	}
+
+	return json.Marshal(struct {
+		Account         account    `json:"account"`
+		ChangedFields   []string   `json:"changed_fields,omitempty"`
+		RestorableUntil *time.Time `json:"restorable_until,omitempty"`
+	}{a, d.ChangedFields, d.RestorableUntil})
+}
+
+func (d EmailAddressEventData) MarshalJSON() ([]byte, error) {
+	emailAddress, err := protoJSON(d.EmailAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(struct {
+		EmailAddress                  json.RawMessage `json:"email_address"`
+		PreviousPrimaryEmailAddressID string          `json:"previous_primary_email_address_id,omitempty"`
+	}{emailAddress, d.PreviousPrimaryEmailAddressID})
+}
+
+func (d PhoneNumberEventData) MarshalJSON() ([]byte, error) {
+	phoneNumber, err := protoJSON(d.PhoneNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(struct {
+		PhoneNumber json.RawMessage `json:"phone_number"`
+	}{phoneNumber})
+}
+
+// eventJSON encodes protobuf values in events with their proto field names,
+// to match the snake_case of the rest of the event.
+var eventJSON = protojson.MarshalOptions{UseProtoNames: true}
+
+// protoJSON encodes a protobuf message the way protobuf's JSON mapping
+// says to, which encoding/json doesn't know about, e.g. for timestamps.
+// A nil message is encoded as null.
+func protoJSON(m proto.Message) (json.RawMessage, error) {
+	if m == nil || reflect.ValueOf(m).IsNil() {
+		return json.RawMessage("null"), nil
+	}
+	return eventJSON.Marshal(proto.MessageV2(m))
+}
+
+// emit writes a domain event to the transactional outbox.
+func emit(ctx context.Context, tx db.Transaction, eventType, accountID string, data interface{}) error {
+	e := Event{
+		Version:    EventVersion,
+		ID:         xid.New().String(),
+		Type:       eventType,
+		AccountID:  accountID,
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+	}
+	if p, ok := auth.FromContext(ctx); ok {
+		e.ActorID = p.AccountID
+	}
+
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s event: %w", eventType, err)
+	}
+
+	headers := map[string]string{
+		HeaderForEventID:      e.ID,
+		HeaderForEventType:    eventType,
+		HeaderForEventVersion: fmt.Sprint(EventVersion),
+		HeaderForAccountID:    accountID,
+		HeaderForContentType:  "application/json",
+	}
+
+	return enqueue(ctx, tx, TopicForAccountEvents, accountID, payload, headers)
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	clock "github.com/AlpacaLabs/go-timestamp"
+	accountV1 "github.com/AlpacaLabs/protorepo-account-go/alpacalabs/account/v1"
+)
+
+func TestEventDataUsesProtobufJSONMapping(t *testing.T) {
+	createdAt := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	emailAddress := &accountV1.EmailAddress{
+		Id:           "e1",
+		CreatedAt:    clock.TimeToTimestamp(createdAt),
+		EmailAddress: "jane@example.com",
+		AccountId:    "a1",
+	}
+
+	tests := []struct {
+		name string
+		data interface{}
+		path []string
+		want interface{}
+	}{
+		{
+			name: "email address timestamps are RFC 3339",
+			data: EmailAddressEventData{EmailAddress: emailAddress},
+			path: []string{"email_address", "created_at"},
+			want: "2020-01-02T03:04:05Z",
+		},
+		{
+			name: "email address fields use proto names",
+			data: EmailAddressEventData{EmailAddress: emailAddress},
+			path: []string{"email_address", "account_id"},
+			want: "a1",
+		},
+		{
+			name: "nil phone number is null",
+			data: PhoneNumberEventData{},
+			path: []string{"phone_number"},
+			want: nil,
+		},
+		{
+			name: "account contact details use the protobuf mapping",
+			data: AccountEventData{Account: AccountDetails{ID: "a1", EmailAddresses: []*accountV1.EmailAddress{emailAddress}}},
+			path: []string{"account", "email_addresses", "0", "created_at"},
+			want: "2020-01-02T03:04:05Z",
+		},
+		{
+			name: "account fields are kept",
+			data: AccountEventData{Account: AccountDetails{ID: "a1"}, ChangedFields: []string{"username"}},
+			path: []string{"account", "id"},
+			want: "a1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := json.Marshal(tt.data)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+
+			var v interface{}
+			if err := json.Unmarshal(b, &v); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			for _, key := range tt.path {
+				switch node := v.(type) {
+				case map[string]interface{}:
+					v = node[key]
+				case []interface{}:
+					i, err := strconv.Atoi(key)
+					if err != nil || i >= len(node) {
+						t.Fatalf("no element %q in %s", key, b)
+					}
+					v = node[i]
+				default:
+					t.Fatalf("no %q in %s", key, b)
+				}
+			}
+
+			if v != tt.want {
+				t.Errorf("%v = %v, want %v in %s", tt.path, v, tt.want, b)
+			}
+		})
+	}
+}
@@ -0,0 +1,16 @@
+package service
+
+import (
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// validateCreatedRange makes sure a created-at range isn't empty.
+func validateCreatedRange(after, before *time.Time) error {
+	if after != nil && before != nil && !after.Before(*before) {
+		return status.Error(codes.InvalidArgument, "created_after must be before created_before")
+	}
+	return nil
+}
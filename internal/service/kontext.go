@@ -3,10 +3,32 @@ package service
 import (
 	"context"
 
-	"github.com/rs/xid"
+	"github.com/AlpacaLabs/api-account/internal/auth"
 )
 
-func getRequesterID(ctx context.Context) string {
-	// TODO this is wrong. instead, parse the actual value from ctx
-	return xid.New().String()
+// getPrincipal returns the authenticated caller, or ErrUnauthenticated
+// if the request carried no valid bearer token.
+func getPrincipal(ctx context.Context) (auth.Principal, error) {
+	p, ok := auth.FromContext(ctx)
+	if !ok || p.AccountID == "" {
+		return auth.Principal{}, ErrUnauthenticated
+	}
+	return p, nil
+}
+
+// canRead reports whether the principal may read resources owned by accountID.
+func canRead(p auth.Principal, accountID string) bool {
+	return p.AccountID == accountID || p.HasRole(auth.RoleAdmin) || p.HasRole(auth.RoleSupport)
+}
+
+// canWrite reports whether the principal may modify resources owned by accountID.
+func canWrite(p auth.Principal, accountID string) bool {
+	return p.AccountID == accountID || p.HasRole(auth.RoleAdmin)
+}
+
+// mustMask reports whether contact details owned by accountID have to be
+// masked before they're shown to the principal. Only owners and admins
+// get to see raw data.
+func mustMask(p auth.Principal, accountID string) bool {
+	return p.AccountID != accountID && !p.HasRole(auth.RoleAdmin)
 }
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"strings"
+
+	accountV1 "github.com/AlpacaLabs/protorepo-account-go/alpacalabs/account/v1"
+)
+
+// maskEmailAddress masks the address in place.
+func maskEmailAddress(e *accountV1.EmailAddress) {
+	e.EmailAddress = maskEmail(e.EmailAddress)
+}
+
+// maskPhoneNumber masks the number in place.
+func maskPhoneNumber(p *accountV1.PhoneNumber) {
+	p.PhoneNumber = maskPhone(p.PhoneNumber)
+}
+
+// fallbackMask stands in for values too short or malformed to be
+// partially masked.
+const fallbackMask = "***"
+
+func maskEmail(emailAddress string) string {
+	i := strings.LastIndex(emailAddress, "@")
+	if i < 0 {
+		return fallbackMask
+	}
+	user := getMaskedEmailUser(emailAddress[:i])
+	host := getMaskedEmailHost(emailAddress[i+1:])
+	if user == "" || host == "" {
+		return fallbackMask
+	}
+	return user + "@" + host
+}
+
+// getMaskedEmailUser keeps the first two characters of the local part.
+// It returns "" if there is nothing to mask.
+func getMaskedEmailUser(user string) string {
+	r := []rune(user)
+	switch len(r) {
+	case 0:
+		return ""
+	case 1:
+		return user
+	}
+	return string(r[0:2]) + strings.Repeat("*", len(r)-2)
+}
+
+// getMaskedEmailHost keeps the first character of the domain, and every
+// label after the first one. It returns "" if there is nothing to mask.
+func getMaskedEmailHost(host string) string {
+	splits := strings.Split(host, ".")
+	first := []rune(splits[0])
+	if len(first) == 0 {
+		return ""
+	}
+	splits[0] = string(first[0:1]) + strings.Repeat("*", len(first)-1)
+	return strings.Join(splits, ".")
+}
+
+// maskPhone keeps the last two digits of the number.
+func maskPhone(phoneNumber string) string {
+	if len(phoneNumber) <= 2 {
+		return fallbackMask
+	}
+	return phoneNumber[len(phoneNumber)-2:]
+}
@@ -0,0 +1,53 @@
+package service
+
+import "testing"
+
+func TestMaskEmail(t *testing.T) {
+	tests := []struct {
+		name         string
+		emailAddress string
+		want         string
+	}{
+		{"typical", "jdoe@example.com", "jd**@e******.com"},
+		{"one character user", "j@example.com", "j@e******.com"},
+		{"two character user", "jd@example.com", "jd@e******.com"},
+		{"single label host", "jdoe@localhost", "jd**@l********"},
+		{"unicode", "jöhn@bücher.de", "jö**@b*****.de"},
+		{"quoted local part with @", `"a@b"@example.com`, `"a***@e******.com`},
+		{"empty", "", fallbackMask},
+		{"no @", "jdoe", fallbackMask},
+		{"empty user", "@example.com", fallbackMask},
+		{"empty host", "jdoe@", fallbackMask},
+		{"empty first label", "jdoe@.com", fallbackMask},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := maskEmail(tt.emailAddress); got != tt.want {
+				t.Errorf("maskEmail(%q) = %q, want %q", tt.emailAddress, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaskPhone(t *testing.T) {
+	tests := []struct {
+		name        string
+		phoneNumber string
+		want        string
+	}{
+		{"e164", "+14155550123", "23"},
+		{"three digits", "123", "23"},
+		{"two digits", "12", fallbackMask},
+		{"one digit", "1", fallbackMask},
+		{"empty", "", fallbackMask},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := maskPhone(tt.phoneNumber); got != tt.want {
+				t.Errorf("maskPhone(%q) = %q, want %q", tt.phoneNumber, got, tt.want)
+			}
+		})
+	}
+}
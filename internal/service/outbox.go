@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/AlpacaLabs/api-account/internal/db"
+	"github.com/AlpacaLabs/api-account/internal/db/entities"
+	"github.com/golang/protobuf/proto"
+)
+
+const (
+	TopicForEmailAddressConfirmationRequested = "email-address-confirmation-requested"
+	TopicForPhoneNumberConfirmationRequested  = "phone-number-confirmation-requested"
+)
+
+const (
+	// outboxBatchSize caps how many messages are relayed per transaction.
+	outboxBatchSize = 100
+
+	outboxMinRetryDelay = time.Second
+	outboxMaxRetryDelay = 10 * time.Minute
+)
+
+// OutboxPublisher delivers outbox messages to their destination.
+type OutboxPublisher interface {
+	Publish(ctx context.Context, m entities.OutboxMessage) error
+}
+
+// enqueueProto writes a protobuf message to the transactional outbox.
+func enqueueProto(ctx context.Context, tx db.Transaction, topic, key string, pb proto.Message, headers map[string]string) error {
+	payload, err := proto.Marshal(pb)
+	if err != nil {
+		return fmt.Errorf("failed to marshal outbox message for topic %s: %w", topic, err)
+	}
+
+	return enqueue(ctx, tx, topic, key, payload, headers)
+}
+
+// enqueue writes a message to the transactional outbox. It must be called
+// with the same transaction as the change the message describes.
+func enqueue(ctx context.Context, tx db.Transaction, topic, key string, payload []byte, headers map[string]string) error {
+	return tx.CreateOutboxMessage(ctx, entities.NewOutboxMessage(entities.NewOutboxMessageInput{
+		Topic:   topic,
+		Key:     key,
+		Headers: headers,
+		Payload: payload,
+	}))
+}
+
+// RelayOutboxMessages publishes a batch of pending outbox messages.
+// Messages that fail to publish are retried later with exponential backoff.
+// It returns the number of messages that were published.
+func (s Service) RelayOutboxMessages(ctx context.Context, publisher OutboxPublisher) (int, error) {
+	var sent int
+
+	err := s.dbClient.RunInTransaction(ctx, func(ctx context.Context, tx db.Transaction) error {
+		messages, err := tx.GetPendingOutboxMessages(ctx, outboxBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range messages {
+			send, err := s.attachOutboxSecrets(ctx, tx, m)
+			if err == nil && send {
+				err = publisher.Publish(ctx, *m)
+			}
+			if err != nil {
+				nextAttemptAt := time.Now().Add(outboxRetryDelay(m.Attempts))
+				if err := tx.MarkOutboxMessageFailed(ctx, m.ID, nextAttemptAt, err.Error()); err != nil {
+					return err
+				}
+				continue
+			}
+
+			// Messages that no longer need sending are marked as sent
+			// too, so they aren't picked up again.
+			if err := tx.MarkOutboxMessageSent(ctx, m.ID); err != nil {
+				return err
+			}
+			if send {
+				sent++
+			}
+		}
+
+		return nil
+	})
+
+	return sent, err
+}
+
+// attachOutboxSecrets adds the one-time secrets that some messages carry,
+// like confirmation tokens and SMS passcodes. They're minted just before
+// the message is published, rather than when it's enqueued, so they're
+// only ever stored hashed. It returns false if the message no longer
+// needs to be sent.
+func (s Service) attachOutboxSecrets(ctx context.Context, tx db.Transaction, m *entities.OutboxMessage) (bool, error) {
+	if m.Headers == nil {
+		m.Headers = map[string]string{}
+	}
+
+	switch m.Topic {
+	case TopicForEmailAddressConfirmationRequested:
+		return s.attachEmailConfirmationToken(ctx, tx, m)
+	case TopicForPhoneNumberConfirmationRequested:
+		return s.attachPhoneVerificationCode(ctx, tx, m)
+	default:
+		return true, nil
+	}
+}
+
+// PurgeRelayedOutboxMessages deletes messages that were relayed longer ago
+// than the retention period. It returns the number of messages deleted.
+func (s Service) PurgeRelayedOutboxMessages(ctx context.Context) (int, error) {
+	sentBefore := time.Now().Add(-s.config.OutboxRetention)
+
+	var total int
+	for {
+		var n int
+		err := s.dbClient.RunInTransaction(ctx, func(ctx context.Context, tx db.Transaction) error {
+			var err error
+			n, err = tx.DeleteSentOutboxMessages(ctx, sentBefore, purgeBatchSize)
+			return err
+		})
+		if err != nil {
+			return total, err
+		}
+
+		total += n
+		if n < purgeBatchSize {
+			return total, nil
+		}
+	}
+}
+
+// outboxRetryDelay doubles the delay with every failed attempt.
+func outboxRetryDelay(attempts int) time.Duration {
+	delay := outboxMinRetryDelay
+	for i := 0; i < attempts && delay < outboxMaxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > outboxMaxRetryDelay {
+		return outboxMaxRetryDelay
+	}
+	return delay
+}
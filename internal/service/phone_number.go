@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/AlpacaLabs/api-account/internal/db"
+	"github.com/AlpacaLabs/api-account/internal/db/entities"
+	"github.com/AlpacaLabs/api-account/internal/phonenum"
+	"github.com/guregu/null"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const normalizeBatchSize = 100
+
+// parseTextablePhoneNumber validates a phone number that verification
+// codes are going to be texted to.
+func (s Service) parseTextablePhoneNumber(ctx context.Context, phoneNumber string) (phonenum.Number, error) {
+	p, err := s.phones.Parse(ctx, phoneNumber)
+	if err != nil {
+		return phonenum.Number{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if !p.CanReceiveSMS() {
+		return phonenum.Number{}, ErrPhoneNumberCannotReceiveSMS
+	}
+	return p, nil
+}
+
+// NormalizePhoneNumbers rewrites phone numbers stored before E.164
+// normalization, reading them as belonging to the default region.
+// Numbers that don't parse, or whose E.164 form another number already
+// has, keep what was typed in and are marked as being of unknown type;
+// the conflicts are recorded too. It returns how many numbers were parsed,
+// and the conflicts found. If another instance is already normalizing,
+// it does nothing.
+func (s Service) NormalizePhoneNumbers(ctx context.Context) (int, []entities.PhoneNumberConflict, error) {
+	var total int
+	var conflicts []entities.PhoneNumberConflict
+
+	_, err := s.dbClient.RunExclusively(ctx, db.NormalizePhoneNumbersLockID, func(ctx context.Context) error {
+		for {
+			var phoneNumbers []entities.PhoneNumber
+			err := s.dbClient.RunInTransaction(ctx, func(ctx context.Context, tx db.Transaction) error {
+				var err error
+				phoneNumbers, err = tx.GetUnparsedPhoneNumbers(ctx, normalizeBatchSize)
+				return err
+			}, db.ReadOnly)
+			if err != nil {
+				return err
+			}
+
+			for _, p := range phoneNumbers {
+				unknown := p
+				unknown.NumberType = null.StringFrom(phonenum.TypeUnknown)
+
+				parsed, err := s.phones.Parse(ctx, p.PhoneNumber)
+				if err != nil {
+					if err := s.updateParsedPhoneNumber(ctx, unknown); err != nil {
+						return err
+					}
+					continue
+				}
+
+				p.PhoneNumber = parsed.E164
+				p.CountryCode = null.IntFrom(int64(parsed.CountryCode))
+				p.NumberType = null.StringFrom(parsed.Type)
+
+				// Each number gets its own transaction, so one that collides
+				// with another account's number doesn't hold up the rest.
+				var conflict *entities.PhoneNumberConflict
+				err = s.dbClient.RunInTransaction(ctx, func(ctx context.Context, tx db.Transaction) error {
+					// Only numbers in use have to be unique.
+					if !p.DeletedAt.Valid {
+						other, err := tx.GetPhoneNumberByPhoneNumber(ctx, p.PhoneNumber)
+						if err != nil && err != db.ErrNotFound {
+							return err
+						}
+						if other != nil && other.Id != p.ID {
+							conflict = &entities.PhoneNumberConflict{
+								PhoneNumberID:            p.ID,
+								AccountID:                p.AccountID,
+								E164PhoneNumber:          p.PhoneNumber,
+								ConflictingPhoneNumberID: other.Id,
+								ConflictingAccountID:     other.AccountId,
+								DetectedAt:               time.Now(),
+							}
+							if err := tx.PutPhoneNumberConflict(ctx, *conflict); err != nil {
+								return err
+							}
+							return tx.UpdateParsedPhoneNumber(ctx, unknown)
+						}
+					}
+
+					return tx.UpdateParsedPhoneNumber(ctx, p)
+				})
+				if st, ok := status.FromError(err); ok && st.Code() == codes.AlreadyExists {
+					// The E.164 form was taken after we looked.
+					err = s.updateParsedPhoneNumber(ctx, unknown)
+					if err == nil {
+						continue
+					}
+				}
+				if err != nil {
+					return err
+				}
+
+				if conflict != nil {
+					conflicts = append(conflicts, *conflict)
+				} else {
+					total++
+				}
+			}
+
+			if len(phoneNumbers) < normalizeBatchSize {
+				return nil
+			}
+		}
+	})
+
+	return total, conflicts, err
+}
+
+func (s Service) updateParsedPhoneNumber(ctx context.Context, p entities.PhoneNumber) error {
+	return s.dbClient.RunInTransaction(ctx, func(ctx context.Context, tx db.Transaction) error {
+		return tx.UpdateParsedPhoneNumber(ctx, p)
+	})
+}
@@ -9,18 +9,26 @@ import (
 
 // GetPhoneNumber retrieves an phone number by primary key.
 func (s Service) GetPhoneNumber(ctx context.Context, request *accountV1.GetPhoneNumberRequest) (*accountV1.GetPhoneNumberResponse, error) {
-	requesterID := getRequesterID(ctx)
+	principal, err := getPrincipal(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	response := &accountV1.GetPhoneNumberResponse{}
-	err := s.dbClient.RunInTransaction(ctx, func(ctx context.Context, tx db.Transaction) error {
+	err = s.dbClient.RunInTransaction(ctx, func(ctx context.Context, tx db.Transaction) error {
 		phoneNumber, err := tx.GetPhoneNumberByID(ctx, request.Id)
 		if err != nil {
 			return err
 		}
 
-		if phoneNumber.AccountId != requesterID {
+		if !canRead(principal, phoneNumber.AccountId) {
 			return ErrUnowned
 		}
 
+		if mustMask(principal, phoneNumber.AccountId) {
+			maskPhoneNumber(phoneNumber)
+		}
+
 		response.PhoneNumber = phoneNumber
 		return nil
 	})
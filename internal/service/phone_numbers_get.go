@@ -3,50 +3,89 @@ package service
 import (
 	"context"
 
+	"github.com/AlpacaLabs/api-account/internal/auth"
 	"github.com/AlpacaLabs/api-account/internal/db"
 	accountV1 "github.com/AlpacaLabs/protorepo-account-go/alpacalabs/account/v1"
 	paginationV1 "github.com/AlpacaLabs/protorepo-pagination-go/alpacalabs/pagination/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+type ListPhoneNumbersRequest struct {
+	Filter        db.PhoneNumberFilter        `json:"filter"`
+	CursorRequest *paginationV1.CursorRequest `json:"cursor_request"`
+}
+
+type ListPhoneNumbersResponse struct {
+	PhoneNumbers   []*accountV1.PhoneNumber `json:"phone_numbers"`
+	CursorResponse CursorResponse           `json:"cursor_response"`
+}
+
 // GetPhoneNumbers retrieves all phone numbers in the system.
-// Ideally, this function should be locked down and offered for
-// admins only.
+// Only admins and support staff may call this; support staff
+// only get to see masked phone numbers.
 func (s Service) GetPhoneNumbers(ctx context.Context, request *accountV1.GetPhoneNumbersRequest) (*accountV1.GetPhoneNumbersResponse, error) {
-	out := &accountV1.GetPhoneNumbersResponse{}
+	response, err := s.ListPhoneNumbers(ctx, &ListPhoneNumbersRequest{
+		CursorRequest: request.CursorRequest,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &accountV1.GetPhoneNumbersResponse{
+		PhoneNumbers:   response.PhoneNumbers,
+		CursorResponse: response.CursorResponse.ToProtobuf(),
+	}, nil
+}
+
+// ListPhoneNumbers is GetPhoneNumbers with filters.
+func (s Service) ListPhoneNumbers(ctx context.Context, request *ListPhoneNumbersRequest) (*ListPhoneNumbersResponse, error) {
+	principal, err := getPrincipal(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	// Validate cursor
-	if request.CursorRequest == nil {
-		return nil, ErrNilCursorRequest
+	filter := request.Filter
+	page, err := s.newPageRequest(request.CursorRequest, &filter)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateCreatedRange(filter.CreatedAfter, filter.CreatedBefore); err != nil {
+		return nil, err
 	}
-	cursorRequest := *request.CursorRequest
-	if cursorRequest.Count > MaxPageSize {
-		cursorRequest.Count = MaxPageSize
-	} else if cursorRequest.Count == 0 {
-		cursorRequest.Count = DefaultPageSize
+	if filter.CountryCode < 0 || filter.CountryCode > 999 {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid country code: %d", filter.CountryCode)
 	}
 
-	err := s.dbClient.RunInTransaction(ctx, func(ctx context.Context, tx db.Transaction) error {
-		phoneNumbers, err := tx.GetPhoneNumbers(ctx, cursorRequest)
-		if err != nil {
-			return err
+	// Support staff see masked numbers, so they can't sort by them.
+	// They can still filter by country code, which says no more about
+	// a number than its region does.
+	masked := !principal.HasRole(auth.RoleAdmin)
+	if masked {
+		if err := checkSortVisible(page.SortClauses, "phone_number"); err != nil {
+			return nil, err
 		}
+	}
 
-		out.PhoneNumbers = phoneNumbers
-
-		count := len(phoneNumbers)
+	out := &ListPhoneNumbersResponse{}
 
-		out.CursorResponse = &paginationV1.CursorResponse{
-			PreviousCursor: cursorRequest.Cursor,
-			Count:          int32(count),
+	err = s.dbClient.RunInTransaction(ctx, func(ctx context.Context, tx db.Transaction) error {
+		phoneNumbers, info, err := tx.GetPhoneNumbers(ctx, filter, page.PageRequest)
+		if err != nil {
+			return err
 		}
 
-		if count > 0 {
-			// Set the next cursor clients should use so they can continue pagination
-			out.CursorResponse.NextCursor = phoneNumbers[count-1].Id
+		if masked {
+			for _, p := range phoneNumbers {
+				maskPhoneNumber(p)
+			}
 		}
 
+		out.PhoneNumbers = phoneNumbers
+		out.CursorResponse = s.cursorResponse(page, info, len(phoneNumbers))
+
 		return nil
-	})
+	}, db.ReadOnly)
 
 	if err != nil {
 		return nil, err
@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/AlpacaLabs/api-account/internal/db"
+	"github.com/AlpacaLabs/api-account/internal/db/entities"
+	accountV1 "github.com/AlpacaLabs/protorepo-account-go/alpacalabs/account/v1"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	keyPurposePhoneVerification = "phone-verification-code"
+
+	// HeaderForVerificationCode carries the passcode on messages asking
+	// for a phone number to be verified. It's only added when the message
+	// is relayed, so the passcode is never stored in the outbox.
+	HeaderForVerificationCode = "verification-code"
+
+	verificationCodeDigits = 6
+)
+
+type VerifyPhoneNumberRequest struct {
+	PhoneNumberID string `json:"phone_number_id"`
+	Code          string `json:"code"`
+}
+
+type VerifyPhoneNumberResponse struct {
+	PhoneNumber *accountV1.PhoneNumber `json:"phone_number"`
+}
+
+// VerifyPhoneNumber confirms a phone number if the caller enters the
+// passcode that was texted to it. Only a limited number of wrong codes can
+// be entered, however many are sent, before the phone number is locked out
+// for a while.
+func (s Service) VerifyPhoneNumber(ctx context.Context, request *VerifyPhoneNumberRequest) (*VerifyPhoneNumberResponse, error) {
+	principal, err := getPrincipal(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &VerifyPhoneNumberResponse{}
+
+	// Wrong guesses must still be counted, so rather than rolling back
+	// we commit the incremented counter and report the failure afterwards.
+	var wrongCode bool
+
+	err = s.dbClient.RunInTransaction(ctx, func(ctx context.Context, tx db.Transaction) error {
+		p, err := tx.GetPhoneNumberByID(ctx, request.PhoneNumberID)
+		if err != nil {
+			return err
+		}
+		if !canWrite(principal, p.AccountId) {
+			return ErrUnowned
+		}
+
+		v, err := tx.GetPhoneNumberVerification(ctx, p.Id)
+		if err != nil {
+			if err == db.ErrNotFound {
+				return ErrVerificationCodeNotRequested
+			}
+			return err
+		}
+
+		if v.Attempts >= s.config.PhoneVerificationMaxAttempts {
+			if v.LastAttemptAt.Valid && time.Since(v.LastAttemptAt.Time) < s.config.PhoneVerificationLockout {
+				return ErrVerificationCodeLockedOut
+			}
+			if err := tx.ResetPhoneNumberVerificationAttempts(ctx, p.Id); err != nil {
+				return err
+			}
+		}
+		if time.Now().After(v.ExpiresAt) {
+			return ErrVerificationCodeExpired
+		}
+
+		if subtle.ConstantTimeCompare(v.CodeHash, s.hashVerificationCode(p.Id, request.Code)) != 1 {
+			wrongCode = true
+			return tx.IncrementPhoneNumberVerificationAttempts(ctx, p.Id)
+		}
+
+		if err := tx.DeletePhoneNumberVerification(ctx, p.Id); err != nil {
+			return err
+		}
+		if err := tx.ConfirmPhoneNumber(ctx, p.Id); err != nil {
+			return err
+		}
+
+		p.Confirmed = true
+		out.PhoneNumber = p
+
+		return emit(ctx, tx, EventPhoneNumberConfirmed, p.AccountId, PhoneNumberEventData{PhoneNumber: p})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	if wrongCode {
+		return nil, ErrVerificationCodeIncorrect
+	}
+
+	return out, nil
+}
+
+// requestPhoneNumberVerification asks for a passcode to be texted to the
+// phone number. The passcode is generated when the request is relayed.
+// Passcodes can only be sent so often.
+func (s Service) requestPhoneNumberVerification(ctx context.Context, tx db.Transaction, p *accountV1.PhoneNumber) error {
+	v, err := tx.GetPhoneNumberVerification(ctx, p.Id)
+	if err != nil && err != db.ErrNotFound {
+		return err
+	}
+	if v != nil {
+		if next := v.CreatedAt.Add(s.config.PhoneVerificationResendDelay); time.Now().Before(next) {
+			return errVerificationCodeResendDelay(next)
+		}
+	}
+
+	return enqueueProto(ctx, tx, TopicForPhoneNumberConfirmationRequested, p.AccountId, p, nil)
+}
+
+// errVerificationCodeResendDelay tells the caller when another passcode
+// can be sent.
+func errVerificationCodeResendDelay(next time.Time) error {
+	return status.Errorf(codes.ResourceExhausted, "a verification code was sent recently; another can be sent after %s", next.UTC().Format(time.RFC3339))
+}
+
+// attachPhoneVerificationCode generates a fresh passcode for a verification
+// request that's about to be relayed, replacing any previous one. It returns
+// false if the phone number has since been confirmed or unregistered, or a
+// passcode was sent to it too recently, in which case there's nothing to send.
+func (s Service) attachPhoneVerificationCode(ctx context.Context, tx db.Transaction, m *entities.OutboxMessage) (bool, error) {
+	requested := &accountV1.PhoneNumber{}
+	if err := proto.Unmarshal(m.Payload, requested); err != nil {
+		return false, fmt.Errorf("failed to unmarshal phone verification request: %w", err)
+	}
+
+	p, err := tx.GetPhoneNumberByID(ctx, requested.Id)
+	if err == db.ErrNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	if p.Confirmed {
+		return false, nil
+	}
+
+	// Requests that were enqueued together are only sent once.
+	v, err := tx.GetPhoneNumberVerification(ctx, p.Id)
+	if err != nil && err != db.ErrNotFound {
+		return false, err
+	}
+	if v != nil && time.Since(v.CreatedAt) < s.config.PhoneVerificationResendDelay {
+		return false, nil
+	}
+
+	code, err := generateVerificationCode()
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	if err := tx.CreatePhoneNumberVerification(ctx, entities.PhoneNumberVerification{
+		PhoneNumberID: p.Id,
+		CreatedAt:     now,
+		CodeHash:      s.hashVerificationCode(p.Id, code),
+		ExpiresAt:     now.Add(s.config.PhoneVerificationCodeTTL),
+	}); err != nil {
+		return false, err
+	}
+
+	m.Headers[HeaderForVerificationCode] = code
+
+	return true, nil
+}
+
+func generateVerificationCode() (string, error) {
+	max := big.NewInt(1)
+	for i := 0; i < verificationCodeDigits; i++ {
+		max.Mul(max, big.NewInt(10))
+	}
+
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%0*d", verificationCodeDigits, n), nil
+}
+
+// hashVerificationCode binds the code to its phone number, so a leaked
+// hash can't be brute-forced once and replayed against other numbers.
+func (s Service) hashVerificationCode(phoneNumberID, code string) []byte {
+	mac := hmac.New(sha256.New, s.deriveKey(keyPurposePhoneVerification))
+	mac.Write([]byte(phoneNumberID))
+	mac.Write([]byte{0})
+	mac.Write([]byte(code))
+	return mac.Sum(nil)
+}
@@ -7,35 +7,45 @@ import (
 	"github.com/AlpacaLabs/api-account/internal/db"
 	"github.com/AlpacaLabs/api-account/internal/db/entities"
 	accountV1 "github.com/AlpacaLabs/protorepo-account-go/alpacalabs/account/v1"
-	paginationV1 "github.com/AlpacaLabs/protorepo-pagination-go/alpacalabs/pagination/v1"
-	"github.com/badoux/checkmail"
-	"github.com/ttacon/libphonenumber"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // RegisterEmailAddress creates an email address entity for a
 // given email address and account ID.
 func (s Service) RegisterEmailAddress(ctx context.Context, request *accountV1.RegisterEmailAddressRequest) (*accountV1.RegisterEmailAddressResponse, error) {
-	emailAddress := request.EmailAddress
 	accountID := request.AccountId
 
 	// Validate email address format
-	if err := checkmail.ValidateFormat(emailAddress); err != nil {
+	emailAddress, err := s.emails.Normalize(request.EmailAddress)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := s.domains.Check(emailAddress.Domain()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// Clients may only register email addresses for themselves, unless they're an admin.
+	principal, err := getPrincipal(ctx)
+	if err != nil {
 		return nil, err
 	}
+	if !canWrite(principal, accountID) {
+		return nil, ErrUnowned
+	}
 
 	out := &accountV1.RegisterEmailAddressResponse{}
 
-	err := s.dbClient.RunInTransaction(ctx, func(ctx context.Context, tx db.Transaction) error {
+	err = s.dbClient.RunInTransaction(ctx, func(ctx context.Context, tx db.Transaction) error {
 
 		// Does the account already exist?
-		// TODO ideally this should come from request context rather than a protobuf field.
-		//  clients shouldn't be allowed to register email addresses for other users.
-		if _, err := tx.GetAccountByID(ctx, accountID); err != nil {
+		account, err := tx.GetAccountByID(ctx, accountID)
+		if err != nil {
 			return fmt.Errorf("no account found for id: %s", accountID)
 		}
 
 		// Is the email already registered?
-		email, err := tx.GetEmailAddressByEmailAddress(ctx, emailAddress)
+		email, err := tx.GetEmailAddressByEmailAddress(ctx, emailAddress.Canonical)
 
 		// Check for internal errors
 		if err != nil && err != db.ErrNotFound {
@@ -45,35 +55,45 @@ func (s Service) RegisterEmailAddress(ctx context.Context, request *accountV1.Re
 		// If the email isn't found, then no one has registered it.
 		if err == db.ErrNotFound || email == nil {
 
-			// Get their email addresses to determine if the one they want to register
-			// should be automatically set to primary
-			var isFirstEmailRegistered bool
-			if emailAddresses, err := tx.GetEmailAddressesForAccount(ctx, accountID, paginationV1.CursorRequest{
-				// A user can't have more than 20 email addresses, right??
-				Count: 20,
-			}); err != nil {
-				if err == db.ErrNotFound {
-					isFirstEmailRegistered = true
-				} else {
+			// Accounts created without an email address don't have a primary
+			// one yet, in which case the first one registered becomes primary.
+			isFirstEmailRegistered := !account.PrimaryEmailAddressID.Valid
+
+			// Create an email address record
+			e := entities.NewEmailAddress(entities.NewEmailAddressInput{
+				Primary:               isFirstEmailRegistered,
+				EmailAddress:          emailAddress.Display,
+				CanonicalEmailAddress: emailAddress.Canonical,
+				CanonicalRules:        s.emails.Rules(),
+				AccountID:             accountID,
+			})
+			if err := tx.CreateEmailAddress(ctx, e); err != nil {
+				return err
+			}
+			if isFirstEmailRegistered {
+				if err := tx.UpdatePrimaryEmailAddress(ctx, accountID, e.ID); err != nil {
 					return err
 				}
-			} else if len(emailAddresses) == 0 {
-				isFirstEmailRegistered = true
 			}
 
-			// Create an email address record
-			if err := tx.CreateEmailAddress(ctx, entities.NewEmailAddress(entities.NewEmailAddressInput{
-				Primary:      isFirstEmailRegistered,
-				EmailAddress: emailAddress,
-				AccountID:    accountID,
-			})); err != nil {
+			out.EmailAddress = e.ToProtobuf()
+
+			if err := emit(ctx, tx, EventEmailAddressRegistered, accountID, EmailAddressEventData{EmailAddress: out.EmailAddress}); err != nil {
 				return err
 			}
 		} else {
 			if email.AccountId != accountID {
 				return ErrEmailAlreadyRegisteredByDifferentAccount
-			} else {
-				// TODO add transactional outbox record to resend confirmation email
+			}
+
+			out.EmailAddress = email
+		}
+
+		// Ask for the email address to be confirmed,
+		// which resends the confirmation email if it was already registered.
+		if !out.EmailAddress.Confirmed {
+			if err := s.requestEmailConfirmation(ctx, tx, out.EmailAddress); err != nil {
+				return err
 			}
 		}
 
@@ -88,27 +108,34 @@ func (s Service) RegisterEmailAddress(ctx context.Context, request *accountV1.Re
 }
 
 func (s Service) RegisterPhoneNumber(ctx context.Context, request *accountV1.RegisterPhoneNumberRequest) (*accountV1.RegisterPhoneNumberResponse, error) {
-	phoneNumber := request.PhoneNumber
 	accountID := request.AccountId
 
-	// Validate email address format
-	if _, err := libphonenumber.Parse(phoneNumber, "US"); err != nil {
+	// Validate phone number
+	phoneNumber, err := s.parseTextablePhoneNumber(ctx, request.PhoneNumber)
+	if err != nil {
 		return nil, err
 	}
 
+	// Clients may only register phone numbers for themselves, unless they're an admin.
+	principal, err := getPrincipal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !canWrite(principal, accountID) {
+		return nil, ErrUnowned
+	}
+
 	out := &accountV1.RegisterPhoneNumberResponse{}
 
-	err := s.dbClient.RunInTransaction(ctx, func(ctx context.Context, tx db.Transaction) error {
+	err = s.dbClient.RunInTransaction(ctx, func(ctx context.Context, tx db.Transaction) error {
 
 		// Does the account already exist?
-		// TODO ideally this should come from request context rather than a protobuf field.
-		//  clients shouldn't be allowed to register phone numbers for other users.
 		if _, err := tx.GetAccountByID(ctx, accountID); err != nil {
 			return fmt.Errorf("no account found for id: %s", accountID)
 		}
 
 		// Is the phone number already registered?
-		entity, err := tx.GetPhoneNumberByPhoneNumber(ctx, phoneNumber)
+		entity, err := tx.GetPhoneNumberByPhoneNumber(ctx, phoneNumber.E164)
 
 		// Check for internal errors
 		if err != nil && err != db.ErrNotFound {
@@ -119,17 +146,34 @@ func (s Service) RegisterPhoneNumber(ctx context.Context, request *accountV1.Reg
 		if err == db.ErrNotFound || entity == nil {
 
 			// Create a phone number record
-			if err := tx.CreatePhoneNumber(ctx, entities.NewPhoneNumber(entities.NewPhoneNumberInput{
-				PhoneNumber: phoneNumber,
+			p := entities.NewPhoneNumber(entities.NewPhoneNumberInput{
+				PhoneNumber: phoneNumber.E164,
+				CountryCode: phoneNumber.CountryCode,
+				NumberType:  phoneNumber.Type,
 				AccountID:   accountID,
-			})); err != nil {
+			})
+			if err := tx.CreatePhoneNumber(ctx, p); err != nil {
+				return err
+			}
+
+			out.PhoneNumber = p.ToProtobuf()
+
+			if err := emit(ctx, tx, EventPhoneNumberRegistered, accountID, PhoneNumberEventData{PhoneNumber: out.PhoneNumber}); err != nil {
 				return err
 			}
 		} else {
 			if entity.AccountId != accountID {
 				return ErrPhoneAlreadyRegisteredByDifferentAccount
-			} else {
-				// TODO add transactional outbox record to resend confirmation SMS
+			}
+
+			out.PhoneNumber = entity
+		}
+
+		// Ask for the phone number to be confirmed,
+		// which texts a new verification code if it was already registered.
+		if !out.PhoneNumber.Confirmed {
+			if err := s.requestPhoneNumberVerification(ctx, tx, out.PhoneNumber); err != nil {
+				return err
 			}
 		}
 
@@ -1,18 +1,75 @@
 package service
 
 import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
 	"github.com/AlpacaLabs/api-account/internal/configuration"
 	"github.com/AlpacaLabs/api-account/internal/db"
+	"github.com/AlpacaLabs/api-account/internal/emailaddr"
+	"github.com/AlpacaLabs/api-account/internal/phonenum"
+	"github.com/AlpacaLabs/api-account/internal/username"
 )
 
 type Service struct {
-	config   configuration.Config
-	dbClient db.Client
+	config     configuration.Config
+	dbClient   db.Client
+	signingKey []byte
+	signup     signupPolicy
+	emails     emailaddr.Normalizer
+	domains    *emailaddr.DomainPolicy
+	phones     phonenum.Parser
+	usernames  *username.Policy
 }
 
-func NewService(config configuration.Config, dbClient db.Client) Service {
-	return Service{
-		config:   config,
-		dbClient: dbClient,
+func NewService(config configuration.Config, dbClient db.Client) (Service, error) {
+	if config.SigningKeyFile == "" {
+		return Service{}, errors.New("no signing key file configured")
+	}
+
+	b, err := ioutil.ReadFile(config.SigningKeyFile)
+	if err != nil {
+		return Service{}, fmt.Errorf("failed to read signing key file: %w", err)
+	}
+
+	signup, err := newSignupPolicy(config.SignupRequiredIdentifiers)
+	if err != nil {
+		return Service{}, err
+	}
+
+	phones, err := phonenum.NewParser(config)
+	if err != nil {
+		return Service{}, err
 	}
+
+	s := Service{
+		config:     config,
+		dbClient:   dbClient,
+		signingKey: bytes.TrimSpace(b),
+		signup:     signup,
+		emails:     emailaddr.NewNormalizer(config),
+		domains:    emailaddr.NewDomainPolicy(),
+		phones:     phones,
+		usernames:  username.NewPolicy(),
+	}
+
+	// A configured list that can't be read is a mistake better caught
+	// at startup than by accepting disposable addresses.
+	if err := s.loadDisposableDomains(); err != nil {
+		return Service{}, err
+	}
+
+	return s, nil
+}
+
+// deriveKey derives a key for a single purpose from the signing key,
+// so that e.g. a confirmation token signature can't be replayed elsewhere.
+func (s Service) deriveKey(purpose string) []byte {
+	mac := hmac.New(sha256.New, s.signingKey)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
 }
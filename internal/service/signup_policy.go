@@ -0,0 +1,55 @@
+package service
+
+import (
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	FieldEmailAddress = "email_address"
+	FieldPhoneNumber  = "phone_number"
+)
+
+var ErrNoAccountIdentifiers = status.Error(codes.InvalidArgument, "at least one of username, email_address or phone_number is required")
+
+// signupPolicy decides which identifiers a new account must be created with.
+type signupPolicy struct {
+	required map[string]bool
+}
+
+func newSignupPolicy(requiredIdentifiers []string) (signupPolicy, error) {
+	p := signupPolicy{required: make(map[string]bool)}
+	for _, id := range requiredIdentifiers {
+		switch id {
+		case FieldUsername, FieldEmailAddress, FieldPhoneNumber:
+			p.required[id] = true
+		default:
+			return signupPolicy{}, fmt.Errorf("unknown signup identifier: %s", id)
+		}
+	}
+	return p, nil
+}
+
+// check makes sure every required identifier was given,
+// and that the account will have at least one.
+func (p signupPolicy) check(username, emailAddress, phoneNumber string) error {
+	given := map[string]bool{
+		FieldUsername:     username != "",
+		FieldEmailAddress: emailAddress != "",
+		FieldPhoneNumber:  phoneNumber != "",
+	}
+
+	for _, id := range []string{FieldUsername, FieldEmailAddress, FieldPhoneNumber} {
+		if p.required[id] && !given[id] {
+			return status.Errorf(codes.InvalidArgument, "%s is required", id)
+		}
+	}
+
+	if !given[FieldUsername] && !given[FieldEmailAddress] && !given[FieldPhoneNumber] {
+		return ErrNoAccountIdentifiers
+	}
+
+	return nil
+}
@@ -8,20 +8,28 @@ import (
 )
 
 func (s Service) UnregisterEmailAddress(ctx context.Context, request *accountV1.UnregisterEmailAddressRequest) (*accountV1.UnregisterEmailAddressResponse, error) {
-	requesterID := getRequesterID(ctx)
+	principal, err := getPrincipal(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	emailAddressID := request.EmailAddressId
 
-	err := s.dbClient.RunInTransaction(ctx, func(ctx context.Context, tx db.Transaction) error {
-		if e, err := tx.GetEmailAddressByID(ctx, emailAddressID); err != nil {
+	err = s.dbClient.RunInTransaction(ctx, func(ctx context.Context, tx db.Transaction) error {
+		e, err := tx.GetEmailAddressByID(ctx, emailAddressID)
+		if err != nil {
 			return err
+		} else if !canWrite(principal, e.AccountId) {
+			return ErrUnregisterUnownedEmailAddress
 		} else if e.Primary {
 			return ErrUnregisterPrimaryEmailAddress
-		} else if e.AccountId != requesterID {
-			return ErrUnregisterUnownedEmailAddress
 		}
 
-		_, err := tx.DeleteEmailAddress(ctx, emailAddressID)
-		return err
+		if _, err := tx.DeleteEmailAddress(ctx, emailAddressID); err != nil {
+			return err
+		}
+
+		return emit(ctx, tx, EventEmailAddressUnregistered, e.AccountId, EmailAddressEventData{EmailAddress: e})
 	})
 
 	if err != nil {
@@ -32,18 +40,26 @@ func (s Service) UnregisterEmailAddress(ctx context.Context, request *accountV1.
 }
 
 func (s Service) UnregisterPhoneNumber(ctx context.Context, request *accountV1.UnregisterPhoneNumberRequest) (*accountV1.UnregisterPhoneNumberResponse, error) {
-	requesterID := getRequesterID(ctx)
+	principal, err := getPrincipal(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	phoneNumberID := request.PhoneNumberId
 
-	err := s.dbClient.RunInTransaction(ctx, func(ctx context.Context, tx db.Transaction) error {
-		if e, err := tx.GetPhoneNumberByID(ctx, phoneNumberID); err != nil {
+	err = s.dbClient.RunInTransaction(ctx, func(ctx context.Context, tx db.Transaction) error {
+		p, err := tx.GetPhoneNumberByID(ctx, phoneNumberID)
+		if err != nil {
 			return err
-		} else if e.AccountId != requesterID {
+		} else if !canWrite(principal, p.AccountId) {
 			return ErrUnregisterUnownedPhoneNumber
 		}
 
-		_, err := tx.DeletePhoneNumber(ctx, phoneNumberID)
-		return err
+		if _, err := tx.DeletePhoneNumber(ctx, phoneNumberID); err != nil {
+			return err
+		}
+
+		return emit(ctx, tx, EventPhoneNumberUnregistered, p.AccountId, PhoneNumberEventData{PhoneNumber: p})
 	})
 
 	if err != nil {
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/AlpacaLabs/api-account/internal/db"
+	"github.com/AlpacaLabs/api-account/internal/db/entities"
+	"github.com/AlpacaLabs/api-account/internal/username"
+	"github.com/guregu/null"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// changeUsername gives an account a new username, as long as it hasn't
+// changed its username too recently, and keeps a record of the old one.
+func (s Service) changeUsername(ctx context.Context, tx db.Transaction, account *entities.Account, name username.Username) error {
+	now := time.Now()
+
+	if last, err := tx.GetLatestUsernameChange(ctx, account.ID); err == nil {
+		if next := last.ChangedAt.Add(s.config.UsernameChangeCooldown); now.Before(next) {
+			return errUsernameChangeCooldown(next)
+		}
+	} else if err != db.ErrNotFound {
+		return err
+	}
+
+	if err := s.checkUsernameAvailable(ctx, tx, name, account.ID); err != nil {
+		return err
+	}
+
+	// Setting a username for the first time doesn't release anything.
+	if account.Username.String != "" {
+		skeleton := account.UsernameSkeleton.String
+		if !account.UsernameSkeleton.Valid {
+			skeleton = s.usernames.Skeleton(account.Username.String)
+		}
+		c := entities.NewUsernameChange(account.ID, account.Username.String, skeleton, now)
+		if err := tx.CreateUsernameChange(ctx, c); err != nil {
+			return err
+		}
+	}
+
+	account.Username = null.StringFrom(name.Display)
+	account.UsernameFolded = null.StringFrom(name.Folded)
+	account.UsernameSkeleton = null.StringFrom(name.Skeleton)
+
+	return nil
+}
+
+// checkUsernameAvailable makes sure no account other than accountID has
+// a username that is the same as, or looks like, the given one, and that
+// no other account gave up such a username within the hold period.
+func (s Service) checkUsernameAvailable(ctx context.Context, tx db.Transaction, name username.Username, accountID string) error {
+	// Accounts that haven't had their skeleton backfilled yet
+	// can still only be matched exactly.
+	if existing, err := tx.GetAccountByUsername(ctx, name.Display); err == nil {
+		if existing.ID != accountID {
+			return ErrUsernameAlreadyTaken
+		}
+	} else if err != db.ErrNotFound {
+		return err
+	}
+
+	if existing, err := tx.GetAccountByUsernameSkeleton(ctx, name.Skeleton); err == nil {
+		if existing.ID != accountID {
+			return ErrUsernameTooSimilar
+		}
+	} else if err != db.ErrNotFound {
+		return err
+	}
+
+	// Accounts can take back their own old usernames, but no one else can
+	// until the hold is over.
+	heldSince := time.Now().Add(-s.config.UsernameHoldPeriod)
+	if _, err := tx.GetUsernameChangeBySkeleton(ctx, name.Skeleton, heldSince, accountID); err == nil {
+		return ErrUsernameOnHold
+	} else if err != db.ErrNotFound {
+		return err
+	}
+
+	return nil
+}
+
+// errUsernameChangeCooldown tells the caller when they can next change
+// their username.
+func errUsernameChangeCooldown(next time.Time) error {
+	return status.Errorf(codes.FailedPrecondition, "username was changed recently; it can be changed again after %s", next.UTC().Format(time.RFC3339))
+}
+
+// NormalizeUsernames stores skeletons and folded forms for usernames that
+// were set before those existed. Usernames that look like one already taken
+// by another account are left without a skeleton, and keep working as they
+// did before.
+// It returns how many usernames were updated. If another instance is
+// already normalizing, it does nothing.
+func (s Service) NormalizeUsernames(ctx context.Context) (int, error) {
+	var total int
+	_, err := s.dbClient.RunExclusively(ctx, db.NormalizeUsernamesLockID, func(ctx context.Context) error {
+		var afterID string
+		for {
+			var accounts []*entities.Account
+			err := s.dbClient.RunInTransaction(ctx, func(ctx context.Context, tx db.Transaction) error {
+				var err error
+				accounts, err = tx.GetAccountsWithUnnormalizedUsernames(ctx, afterID, normalizeBatchSize)
+				return err
+			}, db.ReadOnly)
+			if err != nil {
+				return err
+			}
+
+			for _, a := range accounts {
+				afterID = a.ID
+
+				// Folded forms aren't unique, so they're stored on their own
+				// and don't wait on the skeleton.
+				if !a.UsernameFolded.Valid {
+					folded := s.usernames.Fold(a.Username.String)
+					err := s.dbClient.RunInTransaction(ctx, func(ctx context.Context, tx db.Transaction) error {
+						return tx.UpdateUsernameFolded(ctx, a.ID, folded)
+					})
+					if err != nil {
+						return err
+					}
+				}
+
+				if !a.UsernameSkeleton.Valid {
+					skeleton := s.usernames.Skeleton(a.Username.String)
+
+					// Each username gets its own transaction, so one that collides
+					// with another account's username doesn't hold up the rest.
+					err := s.dbClient.RunInTransaction(ctx, func(ctx context.Context, tx db.Transaction) error {
+						return tx.UpdateUsernameSkeleton(ctx, a.ID, skeleton)
+					})
+					if st, ok := status.FromError(err); ok && st.Code() == codes.AlreadyExists {
+						continue
+					} else if err != nil {
+						return err
+					}
+				}
+				total++
+			}
+
+			if len(accounts) < normalizeBatchSize {
+				return nil
+			}
+		}
+	})
+
+	return total, err
+}
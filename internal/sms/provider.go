@@ -0,0 +1,35 @@
+package sms
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/AlpacaLabs/api-account/internal/configuration"
+	log "github.com/sirupsen/logrus"
+)
+
+const ProviderLog = "log"
+
+// Provider sends text messages.
+type Provider interface {
+	Send(ctx context.Context, to, body string) error
+}
+
+// NewProvider returns the provider selected in the config.
+func NewProvider(config configuration.Config) (Provider, error) {
+	switch config.SMSProvider {
+	case ProviderLog:
+		return LogProvider{}, nil
+	default:
+		return nil, fmt.Errorf("unknown SMS provider: %s", config.SMSProvider)
+	}
+}
+
+// LogProvider logs text messages instead of sending them.
+// It's meant for local development.
+type LogProvider struct{}
+
+func (LogProvider) Send(ctx context.Context, to, body string) error {
+	log.Infof("SMS to %s: %s", to, body)
+	return nil
+}
@@ -0,0 +1,284 @@
+package username
+
+import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	MinLength = 4
+	MaxLength = 25
+)
+
+var (
+	ErrInvalidLength     = fmt.Errorf("username must be between %d and %d characters long", MinLength, MaxLength)
+	ErrInvalidCharacters = errors.New("username may only contain letters, digits, '.', '_' and '-'")
+	ErrInvalidStart      = errors.New("username must start with a letter or digit")
+	ErrInvalidSeparators = errors.New("username may not end with, or repeat, '.', '_' or '-'")
+	ErrMixedScripts      = errors.New("username may not mix letters from different scripts")
+	ErrReserved          = errors.New("username is reserved")
+	ErrBlocked           = errors.New("username is not allowed")
+)
+
+var (
+	//go:embed confusables.txt
+	confusablesFile string
+
+	//go:embed reserved.txt
+	reservedFile string
+
+	//go:embed blocked.txt
+	blockedFile string
+)
+
+// scriptGroups are sets of scripts that are routinely written together,
+// so usernames may mix them.
+var scriptGroups = [][]*unicode.RangeTable{
+	{unicode.Han, unicode.Hiragana, unicode.Katakana},
+	{unicode.Han, unicode.Hangul},
+	{unicode.Han, unicode.Bopomofo},
+}
+
+// Username is a valid username in the two forms it's stored in.
+type Username struct {
+	// Display is the username as typed, in NFKC.
+	Display string
+
+	// Folded is Display with case folded away, for looking the username
+	// up however it's capitalized.
+	Folded string
+
+	// Skeleton is what the username looks like, ignoring case, separators
+	// and lookalike characters. Usernames with the same skeleton could be
+	// mistaken for each other, so skeletons have to be unique.
+	Skeleton string
+}
+
+// Policy decides which usernames may be registered.
+type Policy struct {
+	confusables map[rune]string
+	reserved    map[string]bool
+	blocked     map[string]bool
+}
+
+func NewPolicy() *Policy {
+	p := &Policy{
+		confusables: parseConfusables(confusablesFile),
+		reserved:    map[string]bool{},
+		blocked:     map[string]bool{},
+	}
+	for _, name := range parseList(reservedFile) {
+		p.reserved[p.Skeleton(name)] = true
+	}
+	for _, word := range parseList(blockedFile) {
+		p.blocked[p.Skeleton(word)] = true
+	}
+	return p
+}
+
+// Normalize returns the form usernames are stored in, so lookups by what
+// a user typed match what they registered.
+func (p *Policy) Normalize(username string) string {
+	return norm.NFKC.String(username)
+}
+
+// Fold returns the form usernames are looked up by when case doesn't
+// matter. Unlike a skeleton, it keeps separators and lookalike characters,
+// so usernames that only look alike fold differently.
+func (p *Policy) Fold(username string) string {
+	return norm.NFKC.String(cases.Fold().String(norm.NFKC.String(username)))
+}
+
+// Check validates a username and returns its forms.
+func (p *Policy) Check(username string) (Username, error) {
+	display := p.Normalize(username)
+
+	if n := utf8.RuneCountInString(display); n < MinLength || n > MaxLength {
+		return Username{}, ErrInvalidLength
+	}
+
+	var prev rune
+	for i, r := range display {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+		case unicode.Is(unicode.Mn, r) && i > 0 && !isSeparator(prev):
+			// Combining marks may follow letters and digits.
+		case isSeparator(r):
+			if i == 0 {
+				return Username{}, ErrInvalidStart
+			}
+			if isSeparator(prev) {
+				return Username{}, ErrInvalidSeparators
+			}
+		default:
+			return Username{}, ErrInvalidCharacters
+		}
+		prev = r
+	}
+	if isSeparator(prev) {
+		return Username{}, ErrInvalidSeparators
+	}
+
+	if !singleScript(display) {
+		return Username{}, ErrMixedScripts
+	}
+
+	skeleton := p.Skeleton(display)
+	if p.reserved[skeleton] {
+		return Username{}, ErrReserved
+	}
+	if p.blocked[skeleton] {
+		return Username{}, ErrBlocked
+	}
+	for _, word := range words(display) {
+		if p.blocked[p.Skeleton(word)] {
+			return Username{}, ErrBlocked
+		}
+	}
+
+	return Username{Display: display, Folded: p.Fold(display), Skeleton: skeleton}, nil
+}
+
+// Skeleton works out what a username looks like, after UTS #39: it's
+// decomposed, lookalike characters are replaced by what they look like,
+// and case and separators are dropped.
+func (p *Policy) Skeleton(username string) string {
+	s := p.replaceConfusables(norm.NFD.String(username))
+	s = cases.Fold().String(s)
+	s = p.replaceConfusables(s)
+	return norm.NFD.String(s)
+}
+
+func (p *Policy) replaceConfusables(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if isSeparator(r) {
+			continue
+		}
+		if target, ok := p.confusables[r]; ok {
+			b.WriteString(target)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// words splits a username into the words it's made of, at separators
+// and where a lowercase letter is followed by an uppercase one. Blocked
+// words are only matched whole, so they don't catch innocent names they
+// happen to be part of, like "Scunthorpe" or "mishit".
+func words(username string) []string {
+	var out []string
+	var word []rune
+	var prev rune
+	for _, r := range username {
+		if isSeparator(r) || (unicode.IsLower(prev) && unicode.IsUpper(r)) {
+			if len(word) > 0 {
+				out = append(out, string(word))
+			}
+			word = nil
+		}
+		if !isSeparator(r) {
+			word = append(word, r)
+		}
+		prev = r
+	}
+	if len(word) > 0 {
+		out = append(out, string(word))
+	}
+	return out
+}
+
+func isSeparator(r rune) bool {
+	return r == '.' || r == '_' || r == '-'
+}
+
+// singleScript reports whether the letters of s all belong to one script,
+// or to one group of scripts that are written together.
+func singleScript(s string) bool {
+	scripts := map[*unicode.RangeTable]bool{}
+	for _, r := range s {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		for _, table := range unicode.Scripts {
+			if table != unicode.Common && table != unicode.Inherited && unicode.Is(table, r) {
+				scripts[table] = true
+				break
+			}
+		}
+	}
+	if len(scripts) <= 1 {
+		return true
+	}
+
+	for _, group := range scriptGroups {
+		inGroup := 0
+		for _, table := range group {
+			if scripts[table] {
+				inGroup++
+			}
+		}
+		if inGroup == len(scripts) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseConfusables reads lines like "0430 ; 0061 # comment",
+// mapping a code point to the code points it looks like.
+func parseConfusables(s string) map[rune]string {
+	confusables := map[rune]string{}
+	for _, line := range parseList(s) {
+		fields := strings.Split(line, ";")
+		if len(fields) != 2 {
+			panic(fmt.Sprintf("malformed confusable: %q", line))
+		}
+		source := parseCodePoints(fields[0])
+		if len(source) != 1 {
+			panic(fmt.Sprintf("malformed confusable: %q", line))
+		}
+		confusables[source[0]] = string(parseCodePoints(fields[1]))
+	}
+	return confusables
+}
+
+func parseCodePoints(s string) []rune {
+	var runes []rune
+	for _, hex := range strings.Fields(s) {
+		r, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil {
+			panic(fmt.Sprintf("malformed code point: %q", hex))
+		}
+		runes = append(runes, rune(r))
+	}
+	return runes
+}
+
+// parseList reads one entry per line, dropping blanks and # comments.
+func parseList(s string) []string {
+	var entries []string
+	scanner := bufio.NewScanner(strings.NewReader(s))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			entries = append(entries, line)
+		}
+	}
+	return entries
+}
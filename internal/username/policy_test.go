@@ -0,0 +1,116 @@
+package username
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	p := NewPolicy()
+
+	tests := []struct {
+		name    string
+		in      string
+		wantErr error
+	}{
+		{"plain", "jane_doe", nil},
+		{"digits", "jane1985", nil},
+		{"separators between words", "jane.doe-smith", nil},
+		{"single script other than latin", "ΑλέξηςΠ", nil},
+		{"han with kana", "山田たろう", nil},
+		{"too short", "abc", ErrInvalidLength},
+		{"too long", strings.Repeat("a", MaxLength+1), ErrInvalidLength},
+		{"space", "jane doe", ErrInvalidCharacters},
+		{"symbol", "jane@doe", ErrInvalidCharacters},
+		{"starts with a separator", "_jane", ErrInvalidStart},
+		{"ends with a separator", "jane_", ErrInvalidSeparators},
+		{"repeated separators", "jane__doe", ErrInvalidSeparators},
+		{"latin with cyrillic", "pаypal", ErrMixedScripts},
+		{"latin with greek", "jοhn", ErrMixedScripts},
+		{"reserved", "admin", ErrReserved},
+		{"reserved in another case", "ADMIN", ErrReserved},
+		{"reserved with a lookalike digit", "r00t", ErrReserved},
+		{"reserved with separators", "no_reply", ErrReserved},
+		{"reserved in cyrillic lookalikes", "һеӏр", ErrReserved},
+		{"blocked", "shit", ErrBlocked},
+		{"blocked spaced out", "s.h.i.t", ErrBlocked},
+		{"blocked with a lookalike", "wh0re", ErrBlocked},
+		{"blocked word between separators", "big_shit", ErrBlocked},
+		{"blocked word after a capital", "BigShit", ErrBlocked},
+		{"blocked word inside a place name", "Scunthorpe", nil},
+		{"blocked word inside another word", "mishit", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := p.Check(tt.in)
+			if err != tt.wantErr {
+				t.Errorf("Check(%q) error = %v, want %v", tt.in, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckNormalizes(t *testing.T) {
+	p := NewPolicy()
+
+	// Fullwidth letters are compatibility characters, which NFKC folds.
+	got, err := p.Check("ｊａｎｅ")
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if got.Display != "jane" {
+		t.Errorf("Display = %q, want %q", got.Display, "jane")
+	}
+}
+
+func TestSkeleton(t *testing.T) {
+	p := NewPolicy()
+
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{"paypal", "paypa1", true},
+		{"paypal", "PayPal", true},
+		{"paypal", "pay.pal", true},
+		{"paypal", "pаypal", true},
+		{"modern", "rnodern", true},
+		{"hello", "he11o", true},
+		{"jane", "jade", false},
+		{"jane", "janes", false},
+		{"ali1", "all1", false},
+		{"kim_x", "klm_x", false},
+		{"eli", "ell", false},
+		{"JIM", "jim", true},
+		{"ıce", "ice", true},
+	}
+
+	for _, tt := range tests {
+		if same := p.Skeleton(tt.a) == p.Skeleton(tt.b); same != tt.same {
+			t.Errorf("Skeleton(%q) == Skeleton(%q) is %v, want %v", tt.a, tt.b, same, tt.same)
+		}
+	}
+}
+
+func TestFold(t *testing.T) {
+	p := NewPolicy()
+
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{"paypal", "PayPal", true},
+		{"Straße", "STRASSE", true},
+		{"ｊａｎｅ", "Jane", true},
+		{"paypal", "paypaI", false},
+		{"paypal", "pay.pal", false},
+		{"paypal", "pаypal", false},
+	}
+
+	for _, tt := range tests {
+		if same := p.Fold(tt.a) == p.Fold(tt.b); same != tt.same {
+			t.Errorf("Fold(%q) == Fold(%q) is %v, want %v", tt.a, tt.b, same, tt.same)
+		}
+	}
+}
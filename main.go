@@ -5,12 +5,22 @@ import (
 
 	"github.com/AlpacaLabs/api-account/internal/app"
 	"github.com/AlpacaLabs/api-account/internal/configuration"
+	log "github.com/sirupsen/logrus"
+	flag "github.com/spf13/pflag"
 )
 
 func main() {
 	c := configuration.LoadConfig()
 	a := app.NewApp(c)
 
+	// api-account migrate up|down|status
+	if flag.Arg(0) == "migrate" {
+		if err := a.Migrate(flag.Arg(1)); err != nil {
+			log.Fatalf("migrate %s failed: %v", flag.Arg(1), err)
+		}
+		return
+	}
+
 	var wg sync.WaitGroup
 
 	wg.Add(1)